@@ -1,73 +1,484 @@
 package config
 
 import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/summonhim/gzgspd/nnet"
 )
 
+// ConfigMacvlan 自动创建的 macvlan 子接口配置
+type ConfigMacvlan struct {
+	Parent string `json:"parent"`
+	Name   string `json:"name"`
+	MAC    string `json:"mac"`
+	Mode   string `json:"mode"`
+	DHCP   string `json:"dhcp"`
+}
+
+// ConfigRuijie 锐捷 ePortal 配置
+type ConfigRuijie struct {
+	URL     string `json:"url"`
+	Service string `json:"service"`
+}
+
+// ConfigSrun 深澜 portal 配置
+type ConfigSrun struct {
+	URL  string `json:"url"`
+	AcID string `json:"ac_id"`
+}
+
+// ConfigDrcom Dr.COM 网页认证配置
+type ConfigDrcom struct {
+	URL     string `json:"url"`
+	Variant string `json:"variant"`
+	Port    int    `json:"port"`
+}
+
+// ConfigTLS 连接 https 门户时的 TLS 选项
+type ConfigTLS struct {
+	CAFile             string   `json:"ca_file"`
+	PinSHA256          []string `json:"pin_sha256"`
+	InsecureSkipVerify bool     `json:"insecure_skip_verify"`
+	MinVersion         string   `json:"min_version"`
+}
+
+// ConfigFormField 表单字段或 Cookie，值可使用模板
+type ConfigFormField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ConfigForm 通用网页表单登录规则
+type ConfigForm struct {
+	URL          string            `json:"url"`
+	PortalMatch  string            `json:"portal_match"`
+	Form         string            `json:"form"`
+	Action       string            `json:"action"`
+	Method       string            `json:"method"`
+	Fields       []ConfigFormField `json:"fields"`
+	Cookies      []ConfigFormField `json:"cookies"`
+	Success      string            `json:"success"`
+	SuccessJSON  string            `json:"success_json"`
+	SuccessValue string            `json:"success_value"`
+	LogoutURL    string            `json:"logout_url"`
+}
+
+var instanceNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9@._-]*$`)
+
 // ConfigInstance 单个实例配置
 type ConfigInstance struct {
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-	Interface  string `json:"interface"`
-	UserAgent  string `json:"user_agent"`
-	KeepAlive  int    `json:"keep_alive"`
-	KAliveLink string `json:"keep_alive_link"`
-	RetryMax   int    `json:"retry_max"`
-	RetryTime  int    `json:"retry_time"`
+	Name       string         `json:"name"`
+	Username   string         `json:"username"`
+	Password   string         `json:"password"`
+	Interface  string         `json:"interface"`
+	UserAgent  string         `json:"user_agent"`
+	KeepAlive  int            `json:"keep_alive"`
+	KAliveLink string         `json:"keep_alive_link"`
+	RetryMax   int            `json:"retry_max"`
+	RetryTime  int            `json:"retry_time"`
+	DNS        []string       `json:"dns"`
+	Netns      string         `json:"netns"`
+	Macvlan    *ConfigMacvlan `json:"macvlan"`
+	MACAddress string         `json:"mac"`
+	SetLinkMAC bool           `json:"set_link_mac"`
+	Site       string         `json:"site"`
+	Provider   string         `json:"provider"`
+	Ruijie     *ConfigRuijie  `json:"ruijie"`
+	Srun       *ConfigSrun    `json:"srun"`
+	Drcom      *ConfigDrcom   `json:"drcom"`
+	Form       *ConfigForm    `json:"form"`
+	TLS        *ConfigTLS     `json:"tls"`
+	Proxy      string         `json:"proxy"`
+
+	// 由 site 解析得到的站点配置
+	SiteProfile SiteProfile `json:"-"`
 }
 
 // Config 总配置
 type Config struct {
 	LogLevel int              `json:"log_level"`
 	LogPath  string           `json:"log_path"`
+	StateDir string           `json:"state_dir"`
+	SiteDir  string           `json:"site_dir"`
+	Include  []string         `json:"include"`
+	Defaults ConfigInstance   `json:"defaults"`
 	Instance []ConfigInstance `json:"instance"`
+
+	// 读取的配置文件与 include 所在的目录，用于监视变化
+	Sources    []string `json:"-"`
+	SourceDirs []string `json:"-"`
 }
 
-// Validate 校验配置内容
+// Validate 校验配置内容，返回所有问题
+// 返回的错误为 ValidationError，Path 指向出错的字段
 func (c *Config) Validate() error {
+	var errs ValidationError
 	if len(c.Instance) == 0 {
-		return fmt.Errorf("at least one instance configuration is required")
+		errs.add("instance", "at least one instance configuration is required")
+	}
+	// name 与 username 区分实例，继承后所有实例的键相同
+	if c.Defaults.Name != "" {
+		errs.add("defaults.name", "defaults may not set name, set it in each instance")
+	}
+	if c.Defaults.Username != "" {
+		errs.add("defaults.username", "defaults may not set username, set it in each instance")
 	}
 
+	keys := make(map[string]int)
+	macvlans := make(map[string]int)
 	for i, inst := range c.Instance {
+		path := fmt.Sprintf("instance[%d]", i)
 		if inst.Username == "" {
-			return fmt.Errorf("instance[%d]'s username cannot be empty", i)
+			errs.add(path+".username", "instance[%d]'s username cannot be empty", i)
 		}
 		if inst.Password == "" {
-			return fmt.Errorf("instance[%d]'s password cannot be empty", i)
+			errs.add(path+".password", "instance[%d]'s password cannot be empty", i)
 		}
 		if inst.KeepAlive <= 0 {
-			return fmt.Errorf("instance[%d]'s keep_alive must be greater than 0", i)
+			errs.add(path+".keep_alive", "instance[%d]'s keep_alive must be greater than 0", i)
+		}
+		if inst.KAliveLink != "" && !validHTTPURL(inst.KAliveLink) {
+			errs.add(path+".keep_alive_link", "instance[%d]'s keep_alive_link '%s' must be an http or https URL", i, inst.KAliveLink)
 		}
 		if inst.RetryMax < 0 {
-			return fmt.Errorf("instance[%d]'s retry_max may not be negative", i)
+			errs.add(path+".retry_max", "instance[%d]'s retry_max may not be negative", i)
 		}
 		if inst.RetryTime <= 0 {
-			return fmt.Errorf("instance[%d]'s retry_time must be greater than 0", i)
+			errs.add(path+".retry_time", "instance[%d]'s retry_time must be greater than 0", i)
+		}
+		if inst.Netns != "" && runtime.GOOS != "linux" {
+			errs.add(path+".netns", "instance[%d]'s netns is only supported on linux", i)
+		}
+		if inst.Macvlan != nil {
+			inst.Macvlan.validate(&errs, i)
+			if inst.Interface != "" {
+				errs.add(path+".interface", "instance[%d]'s interface must be empty when macvlan is set", i)
+			}
+			// 同一命名空间中同名的子接口会被另一个实例当作遗留接口删除
+			name := inst.MacvlanName()
+			if j, ok := macvlans[inst.Netns+"/"+name]; ok {
+				errs.add(path+".macvlan.name", "instance[%d] and instance[%d] use the same macvlan name '%s', set different macvlan names", j, i, name)
+			} else {
+				macvlans[inst.Netns+"/"+name] = i
+			}
+		}
+		// 接口位于其他网络命名空间时无法在此检查
+		if inst.Interface != "" && inst.Netns == "" && !hostHasInterface(inst.Interface) {
+			errs.add(path+".interface", "instance[%d]'s interface '%s' does not exist on this host", i, inst.Interface)
+		}
+		if inst.MACAddress != "" {
+			if _, err := net.ParseMAC(inst.MACAddress); err != nil {
+				errs.add(path+".mac", "instance[%d]'s mac '%s' is invalid: %v", i, inst.MACAddress, err)
+			}
+		}
+		if inst.SetLinkMAC {
+			if runtime.GOOS != "linux" {
+				errs.add(path+".set_link_mac", "instance[%d]'s set_link_mac is only supported on linux", i)
+			}
+			if inst.MACAddress == "" {
+				errs.add(path+".set_link_mac", "instance[%d]'s set_link_mac requires mac", i)
+			}
+			if inst.Macvlan == nil && (inst.Interface == "" || net.ParseIP(inst.Interface) != nil) {
+				errs.add(path+".set_link_mac", "instance[%d]'s set_link_mac requires an interface name or macvlan", i)
+			}
+		}
+		for j, s := range inst.DNS {
+			if !validDNSServer(s) {
+				errs.add(fmt.Sprintf("%s.dns[%d]", path, j), "instance[%d]'s dns server '%s' must be an IP or IP:port", i, s)
+			}
+		}
+
+		switch inst.Provider {
+		case "", "telecom", "ruijie", "srun", "drcom", "form":
+		default:
+			errs.add(path+".provider", "instance[%d]'s provider '%s' is unknown", i, inst.Provider)
 		}
+		if inst.Ruijie != nil && inst.Ruijie.URL != "" && !validHTTPURL(inst.Ruijie.URL) {
+			errs.add(path+".ruijie.url", "instance[%d]'s ruijie url '%s' must be an http or https URL", i, inst.Ruijie.URL)
+		}
+		if inst.Srun != nil && inst.Srun.URL != "" && !validHTTPURL(inst.Srun.URL) {
+			errs.add(path+".srun.url", "instance[%d]'s srun url '%s' must be an http or https URL", i, inst.Srun.URL)
+		}
+		if inst.Drcom != nil {
+			inst.Drcom.validate(&errs, i)
+		}
+		if inst.Form != nil {
+			inst.Form.validate(&errs, i)
+		} else if inst.Provider == "form" {
+			errs.add(path+".form", "instance[%d]'s provider form requires form rules", i)
+		}
+
+		if inst.TLS != nil {
+			inst.TLS.validate(&errs, path+".tls", fmt.Sprintf("instance[%d]'s", i))
+		}
+		if inst.Proxy != "" && !validProxyURL(inst.Proxy) {
+			errs.add(path+".proxy", "instance[%d]'s proxy '%s' must be an http, https or socks5 URL", i, redactURL(inst.Proxy))
+		}
+
+		if inst.Name != "" && !instanceNameRe.MatchString(inst.Name) {
+			errs.add(path+".name", "instance[%d]'s name '%s' may only contain letters, digits, '@', '.', '_' and '-'", i, inst.Name)
+		}
+		// 相同的实例标识会共用状态与会话文件
+		key := inst.Key()
+		if j, ok := keys[key]; ok {
+			errs.add(path, "instance[%d] and instance[%d] have the same key '%s', set different names", j, i, key)
+		} else {
+			keys[key] = i
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
 
+func (d *ConfigDrcom) validate(errs *ValidationError, i int) {
+	path := fmt.Sprintf("instance[%d].drcom", i)
+	if d.URL != "" && !validHTTPURL(d.URL) {
+		errs.add(path+".url", "instance[%d]'s drcom url '%s' must be an http or https URL", i, d.URL)
+	}
+	switch d.Variant {
+	case "", "eportal", "legacy":
+	default:
+		errs.add(path+".variant", "instance[%d]'s drcom variant '%s' must be eportal or legacy", i, d.Variant)
+	}
+	if d.Port < 0 || d.Port > 65535 {
+		errs.add(path+".port", "instance[%d]'s drcom port %d is out of range", i, d.Port)
+	}
+}
+
+func (f *ConfigForm) validate(errs *ValidationError, i int) {
+	path := fmt.Sprintf("instance[%d].form", i)
+	for _, re := range []struct{ name, value string }{{"portal_match", f.PortalMatch}, {"success", f.Success}} {
+		if _, err := regexp.Compile(re.value); err != nil {
+			errs.add(path+"."+re.name, "instance[%d]'s form %s is not a valid regex: %v", i, re.name, err)
+		}
+	}
+	switch strings.ToUpper(f.Method) {
+	case "", "GET", "POST":
+	default:
+		errs.add(path+".method", "instance[%d]'s form method '%s' must be GET or POST", i, f.Method)
+	}
+	if f.SuccessJSON != "" && !strings.HasPrefix(f.SuccessJSON, "$") {
+		errs.add(path+".success_json", "instance[%d]'s form success_json '%s' must be a JSONPath starting with $", i, f.SuccessJSON)
+	}
+	for j, field := range f.Fields {
+		if field.Name == "" {
+			errs.add(fmt.Sprintf("%s.fields[%d].name", path, j), "instance[%d]'s form field %d has no name", i, j)
+		}
+	}
+	for j, cookie := range f.Cookies {
+		if cookie.Name == "" {
+			errs.add(fmt.Sprintf("%s.cookies[%d].name", path, j), "instance[%d]'s form cookie %d has no name", i, j)
+		}
+	}
+}
+
+// validate 校验 TLS 选项，owner 为错误信息中的所属对象
+func (t *ConfigTLS) validate(errs *ValidationError, path string, owner string) {
+	if t.CAFile != "" {
+		data, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			errs.add(path+".ca_file", "%s tls ca_file cannot be read: %v", owner, err)
+		} else if !x509.NewCertPool().AppendCertsFromPEM(data) {
+			errs.add(path+".ca_file", "%s tls ca_file '%s' contains no PEM certificates", owner, t.CAFile)
+		}
+	}
+	for j, pin := range t.PinSHA256 {
+		hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+		if err != nil || len(hash) != sha256.Size {
+			errs.add(fmt.Sprintf("%s.pin_sha256[%d]", path, j), "%s tls pin '%s' must be a base64 SHA-256 hash of a public key", owner, pin)
+		}
+	}
+	switch t.MinVersion {
+	case "", "1.0", "1.1", "1.2", "1.3":
+	default:
+		errs.add(path+".min_version", "%s tls min_version '%s' must be one of 1.0, 1.1, 1.2, 1.3", owner, t.MinVersion)
+	}
+}
+
+func (m *ConfigMacvlan) validate(errs *ValidationError, i int) {
+	path := fmt.Sprintf("instance[%d].macvlan", i)
+	if runtime.GOOS != "linux" {
+		errs.add(path, "instance[%d]'s macvlan is only supported on linux", i)
+	}
+	if m.Parent == "" {
+		errs.add(path+".parent", "instance[%d]'s macvlan parent cannot be empty", i)
+	} else if runtime.GOOS == "linux" && !hostHasInterface(m.Parent) {
+		errs.add(path+".parent", "instance[%d]'s macvlan parent '%s' does not exist on this host", i, m.Parent)
+	}
+	if len(m.Name) > 15 {
+		errs.add(path+".name", "instance[%d]'s macvlan name '%s' is longer than 15 characters", i, m.Name)
+	}
+	if m.MAC != "" {
+		if _, err := net.ParseMAC(m.MAC); err != nil {
+			errs.add(path+".mac", "instance[%d]'s macvlan mac '%s' is invalid: %v", i, m.MAC, err)
+		}
+	}
+	switch m.Mode {
+	case "", "bridge", "private", "vepa", "passthru", "source":
+	default:
+		errs.add(path+".mode", "instance[%d]'s macvlan mode '%s' is not one of bridge, private, vepa, passthru, source", i, m.Mode)
+	}
+	switch m.DHCP {
+	case "", "builtin", "none", "udhcpc", "dhclient":
+	default:
+		errs.add(path+".dhcp", "instance[%d]'s macvlan dhcp '%s' is not one of builtin, none, udhcpc, dhclient", i, m.DHCP)
+	}
+}
+
+// Key 返回实例的唯一标识，用于状态、日志与会话文件
+// 填写 name 时为 name，否则为 用户名@接口，接口由 interface、macvlan 与 netns 得到，自动检测时为 Auto
+func (inst *ConfigInstance) Key() string {
+	if inst.Name != "" {
+		return inst.Name
+	}
+
+	iface := inst.Interface
+	if inst.Macvlan != nil {
+		iface = "macvlan:" + inst.Macvlan.Parent
+		if inst.Macvlan.Name != "" {
+			iface += "/" + inst.Macvlan.Name
+		}
+	}
+	if iface == "" {
+		iface = "Auto"
+	}
+	if inst.Netns != "" {
+		iface = inst.Netns + ":" + iface
+	}
+	return inst.Username + "@" + iface
+}
+
+// MacvlanName 返回 macvlan 子接口的名称，未指定时根据实例标识生成，重启后保持不变
+func (inst *ConfigInstance) MacvlanName() string {
+	if inst.Macvlan.Name != "" {
+		return inst.Macvlan.Name
+	}
+	return nnet.GenerateIfName(inst.Key())
+}
+
+// validDNSServer 检查DNS服务器是否为 "IP" 或 "IP:端口"
+func validDNSServer(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
+	host, _, err := net.SplitHostPort(s)
+	return err == nil && net.ParseIP(host) != nil
+}
+
 // LoadConfig 从文件读取并解析配置
-// 传入Json配置文件路径，返回总配置结构体
-func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+// 传入配置文件路径（格式见 ReadConfigJSON）与 --set 覆盖值，返回总配置结构体
+// 文件中的值依次被 GZGSPD_ 环境变量与 --set 覆盖，之后实例继承 defaults 与内置默认值
+func LoadConfig(path string, sets []string) (*Config, error) {
+	data, locs, errs, err := ReadConfigJSON(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按 文件、环境变量、--set 的顺序合并配置
+	tree := map[string]interface{}{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, syntaxLocation(path, data, err)
+	}
+	included, includeDirs, err := readIncludes(tree, path, locs, &errs)
 	if err != nil {
 		return nil, err
 	}
+	overrides := envOverrides(os.Environ())
+	for _, s := range sets {
+		o, err := parseSet(s)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	if err := applyOverrides(tree, overrides); err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		locs.override(o)
+	}
+	mergeDefaults(tree, locs)
+
+	// 检查未知字段与类型，之后与内容校验的错误一并返回
+	checkTree(tree, reflect.TypeOf(Config{}), "", &errs)
+	if data, err = json.Marshal(tree); err != nil {
+		return nil, err
+	}
 
-	var cfg Config
+	cfg := Config{
+		Sources:    append([]string{path}, included...),
+		SourceDirs: includeDirs,
+	}
 	if err := json.Unmarshal(data, &cfg); err != nil {
+		if len(errs) > 0 {
+			locs.locate(errs)
+			return nil, errs
+		}
 		return nil, err
 	}
 
+	for i := range cfg.Instance {
+		cfg.Instance[i].applyBuiltinDefaults()
+	}
+
 	// 校验配置
 	if err := cfg.Validate(); err != nil {
+		verrs, ok := err.(ValidationError)
+		if !ok {
+			return nil, err
+		}
+		errs = append(errs, verrs...)
+	}
+	if len(errs) > 0 {
+		locs.locate(errs)
+		return nil, errs
+	}
+
+	// 解析实例使用的站点
+	sites, err := LoadSites(cfg.SiteDir)
+	if err != nil {
 		return nil, err
 	}
+	for i := range cfg.Instance {
+		name := cfg.Instance[i].Site
+		if name == "" {
+			name = DefaultSite
+		}
+		site, ok := sites[name]
+		if !ok {
+			return nil, fmt.Errorf("instance[%d]'s site '%s' not found", i, name)
+		}
+		cfg.Instance[i].SiteProfile = site
+	}
 
 	return &cfg, nil
 }
+
+// Redacted 返回隐藏密码后的配置副本，用于打印
+func (c Config) Redacted() Config {
+	if c.Defaults.Password != "" {
+		c.Defaults.Password = "******"
+	}
+	c.Defaults.Proxy = redactURL(c.Defaults.Proxy)
+	c.Instance = append([]ConfigInstance(nil), c.Instance...)
+	for i := range c.Instance {
+		if c.Instance[i].Password != "" {
+			c.Instance[i].Password = "******"
+		}
+		c.Instance[i].Proxy = redactURL(c.Instance[i].Proxy)
+	}
+	return c
+}
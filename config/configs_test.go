@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDefaultsIdentity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+  "defaults": { "name": "dorm", "username": "13412345678", "keep_alive": 5, "retry_time": 5 },
+  "instance": [
+    { "name": "a", "username": "13412345678", "password": "123456" },
+    { "name": "b", "username": "13412345679", "password": "654321" }
+  ]
+}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadConfig(path, nil)
+	errs, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("LoadConfig() error = %v, want ValidationError", err)
+	}
+	want := []string{
+		"config.json:2:17: defaults may not set name, set it in each instance",
+		"config.json:2:33: defaults may not set username, set it in each instance",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("LoadConfig() errors:\n%v\nwant %d errors", errs, len(want))
+	}
+	for i := range want {
+		if !strings.HasSuffix(errs[i].Error(), want[i]) {
+			t.Errorf("LoadConfig() error %d = %v, want %q", i, errs[i], want[i])
+		}
+	}
+}
+
+func TestValidateMacvlanNames(t *testing.T) {
+	instance := func(name, macvlan string) ConfigInstance {
+		return ConfigInstance{
+			Name: name, Username: "13412345678", Password: "123456",
+			KeepAlive: 5, RetryTime: 5, KAliveLink: "http://3.3.3.3",
+			Macvlan: &ConfigMacvlan{Parent: "lo", Name: macvlan},
+		}
+	}
+
+	// 同一用户名的具名实例生成不同的子接口
+	a, b := instance("a", ""), instance("b", "")
+	if a.MacvlanName() == b.MacvlanName() {
+		t.Errorf("instances a and b share generated macvlan name %s", a.MacvlanName())
+	}
+	cfg := Config{Instance: []ConfigInstance{a, b}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	cfg = Config{Instance: []ConfigInstance{instance("a", "mv0"), instance("b", "mv0")}}
+	err := cfg.Validate()
+	errs, ok := err.(ValidationError)
+	if !ok || len(errs) != 1 || errs[0].Path != "instance[1].macvlan.name" {
+		t.Errorf("Validate() = %v, want a duplicate macvlan name error", err)
+	}
+
+	// 不同命名空间中可以同名
+	c := instance("c", "mv0")
+	c.Netns = "blue"
+	cfg = Config{Instance: []ConfigInstance{instance("a", "mv0"), c}}
+	if err := cfg.Validate(); err != nil && runtime.GOOS == "linux" {
+		t.Errorf("Validate() = %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import "fmt"
+
+// BuiltinDefaults 内置默认值，实例与 defaults 均未填写时使用
+var BuiltinDefaults = ConfigInstance{
+	UserAgent:  "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36",
+	KAliveLink: "http://3.3.3.3",
+}
+
+// mergeDefaults 将配置树中的 defaults 逐字段合并到每个实例，实例中已填写的字段优先
+// 对象（如 macvlan）逐字段合并，数组整体替换。被继承的字段指向 defaults 中的位置
+// name 与 username 不被继承，由校验报错
+func mergeDefaults(tree map[string]interface{}, locs locations) {
+	defaults, _ := tree["defaults"].(map[string]interface{})
+	instances, _ := tree["instance"].([]interface{})
+	if len(defaults) == 0 {
+		return
+	}
+	inherited := make(map[string]interface{}, len(defaults))
+	for k, v := range defaults {
+		if k != "name" && k != "username" {
+			inherited[k] = v
+		}
+	}
+
+	for i, v := range instances {
+		inst, _ := v.(map[string]interface{})
+		if inst == nil {
+			inst = map[string]interface{}{}
+			instances[i] = inst
+		}
+		mergeTree(inst, inherited, fmt.Sprintf("instance[%d]", i), "defaults", locs)
+	}
+}
+
+func mergeTree(dst, src map[string]interface{}, dstPath, srcPath string, locs locations) {
+	for k, v := range src {
+		child := dstPath + "." + k
+		cur, ok := dst[k]
+		if !ok || cur == nil {
+			dst[k] = copyTree(v)
+			if loc, ok := locs[srcPath+"."+k]; ok {
+				locs[child] = loc
+			}
+			continue
+		}
+		curMap, ok1 := cur.(map[string]interface{})
+		srcMap, ok2 := v.(map[string]interface{})
+		if ok1 && ok2 {
+			mergeTree(curMap, srcMap, child, srcPath+"."+k, locs)
+		}
+	}
+}
+
+// copyTree 深拷贝配置树，避免多个实例共用同一对象
+func copyTree(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = copyTree(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = copyTree(item)
+		}
+		return s
+	}
+	return v
+}
+
+// applyBuiltinDefaults 为未填写的字段填入内置默认值
+func (inst *ConfigInstance) applyBuiltinDefaults() {
+	if inst.UserAgent == "" {
+		inst.UserAgent = BuiltinDefaults.UserAgent
+	}
+	if inst.KAliveLink == "" {
+		inst.KAliveLink = BuiltinDefaults.KAliveLink
+	}
+}
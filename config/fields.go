@@ -0,0 +1,23 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// jsonFields 返回结构体中以 json 标签名索引的字段，忽略 json:"-" 的字段
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
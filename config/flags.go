@@ -2,23 +2,39 @@ package config
 
 import (
 	"flag"
-	"os"
 )
 
 type Flags struct {
 	ConfigFile        string
 	ActionShowVersion bool
 	ActionTestConfig  bool
+	ActionLogout      bool
+	ActionPrint       bool
+	ActionSchema      bool
+	Sets              []string
+	RecordDir         string
+	ReplayDir         string
 }
 
 func ParseFlags(flags *Flags) {
 	defaultConfigFile := "config.json"
-	if v, ok := os.LookupEnv("GSGZPD_CONFIG_FILE"); ok && v != "" {
+	if v := ConfigFileFromEnv(); v != "" {
 		defaultConfigFile = v
 	}
 
 	flag.StringVar(&flags.ConfigFile, "config", defaultConfigFile, "Specify the configuration file path.")
 	flag.BoolVar(&flags.ActionShowVersion, "version", false, "Display current version of gzgspd.")
 	flag.BoolVar(&flags.ActionTestConfig, "test", false, "Test configuration and exit.")
+	flag.BoolVar(&flags.ActionLogout, "logout", false, "Log out all instances using the saved session state and exit.")
+	flag.BoolVar(&flags.ActionPrint, "print", false, "With --test, print the merged configuration with secrets redacted.")
+	flag.Func("set", "Override a configuration field, e.g. instance[0].keep_alive=10. Can be repeated.", func(s string) error {
+		flags.Sets = append(flags.Sets, s)
+		return nil
+	})
+	flag.StringVar(&flags.RecordDir, "record", "", "Record every portal HTTP request and response to this directory as .har files, with passwords redacted.")
+	flag.StringVar(&flags.ReplayDir, "replay", "", "Log in and out once for each instance against a capture made with --record, without network access, and exit.")
 	flag.Parse()
+
+	// gzgspd schema：输出配置文件的 JSON Schema
+	flags.ActionSchema = flag.Arg(0) == "schema"
 }
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ReadConfigJSON 读取配置文件并按扩展名转换为等价的 JSON
+// .json/.jsonc 支持注释与尾随逗号，.yaml/.yml 为 YAML，.toml 为 TOML，
+// 无扩展名（如 OpenWrt 的 /etc/config/gzgspd）按 UCI 格式解析
+// JSON 与 YAML 同时返回各字段的行列位置，JSON 中重复的键作为错误返回
+func ReadConfigJSON(path string) ([]byte, locations, ValidationError, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var tree interface{}
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		out, err := json.Marshal(tree)
+		return out, yamlLocations(path, data), nil, err
+	case ".toml":
+		var tree map[string]interface{}
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		out, err := json.Marshal(tree)
+		return out, locations{}, nil, err
+	case "", ".uci":
+		tree, err := parseUCI(data)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		out, err := json.Marshal(tree)
+		return out, locations{}, nil, err
+	default:
+		out := StripJSONC(data)
+		locs, dups := jsonLocations(path, out)
+		return out, locs, dups, nil
+	}
+}
+
+// StripJSONC 去除 JSONC 中的 // 与 /* */ 注释及尾随逗号
+// 被去除的字符以空格代替并保留换行，解析错误的位置与原文件一致
+func StripJSONC(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	inString := false
+	lastComma := -1
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		if inString {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			lastComma = -1
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := i + 2
+			for ; end+1 < len(out) && !(out[end] == '*' && out[end+1] == '/'); end++ {
+			}
+			end = min(end+2, len(out))
+			for ; i < end; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			i--
+		case c == ',':
+			lastComma = i
+		case c == '}' || c == ']':
+			if lastComma >= 0 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		default:
+			lastComma = -1
+		}
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 目录中被包含的文件扩展名
+var includeExts = map[string]bool{
+	".json":  true,
+	".jsonc": true,
+	".yaml":  true,
+	".yml":   true,
+	".toml":  true,
+}
+
+var (
+	includeArrayPathRe    = regexp.MustCompile(`^\[(\d+)\](.*)$`)
+	includeInstancePathRe = regexp.MustCompile(`^instance\[(\d+)\](.*)$`)
+)
+
+// readIncludes 读取 include 匹配的文件，将其中的实例追加到配置树的 instance
+// include 中的相对路径相对于主配置文件所在目录，目录表示其中所有受支持格式的文件
+// 被包含的文件可以是单个实例对象、实例数组或只含 instance 的对象
+// 返回读取的文件与需要监视的目录
+func readIncludes(tree map[string]interface{}, mainFile string, locs locations, errs *ValidationError) ([]string, []string, error) {
+	patterns, _ := tree["include"].([]interface{})
+	base := filepath.Dir(mainFile)
+	mainAbs, _ := filepath.Abs(mainFile)
+
+	var files, dirs []string
+	seen := map[string]bool{mainAbs: true}
+	for _, v := range patterns {
+		pattern, ok := v.(string)
+		if !ok || pattern == "" {
+			continue
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(base, pattern)
+		}
+
+		var matches []string
+		if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+			entries, err := os.ReadDir(pattern)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, e := range entries {
+				if !e.IsDir() && includeExts[strings.ToLower(filepath.Ext(e.Name()))] {
+					matches = append(matches, filepath.Join(pattern, e.Name()))
+				}
+			}
+			dirs = append(dirs, pattern)
+		} else {
+			m, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, nil, fmt.Errorf("include '%s': %v", pattern, err)
+			}
+			matches = m
+			dirs = append(dirs, globDirs(filepath.Dir(pattern))...)
+		}
+		sort.Strings(matches)
+
+		for _, file := range matches {
+			abs, _ := filepath.Abs(file)
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+			if err := includeFile(tree, file, locs, errs); err != nil {
+				return nil, nil, err
+			}
+			files = append(files, file)
+		}
+	}
+	return files, dirs, nil
+}
+
+// globDirs 返回模式的目录部分需要监视的目录
+// 目录部分含通配符时（如 conf.d/*/x.json 中的 conf.d/*），监视不含通配符的最深一级以发现新目录，
+// 以及各级已匹配的目录
+func globDirs(dir string) []string {
+	base := dir
+	for strings.ContainsAny(base, "*?[") {
+		base = filepath.Dir(base)
+	}
+	dirs := []string{base}
+	for d := dir; d != base; d = filepath.Dir(d) {
+		matches, _ := filepath.Glob(d)
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.IsDir() {
+				dirs = append(dirs, m)
+			}
+		}
+	}
+	return dirs
+}
+
+// includeFile 读取单个被包含的文件
+func includeFile(tree map[string]interface{}, file string, locs locations, errs *ValidationError) error {
+	data, flocs, ferrs, err := ReadConfigJSON(file)
+	if err != nil {
+		return err
+	}
+	*errs = append(*errs, ferrs...)
+
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return syntaxLocation(file, data, err)
+	}
+
+	var items []interface{}
+	var pathRe *regexp.Regexp
+	single := false
+	switch v := root.(type) {
+	case []interface{}:
+		items, pathRe = v, includeArrayPathRe
+	case map[string]interface{}:
+		if list, ok := v["instance"].([]interface{}); ok {
+			for key := range v {
+				if key == "instance" {
+					continue
+				}
+				loc, ok := flocs[key]
+				if !ok {
+					loc = file
+				}
+				*errs = append(*errs, FieldError{Path: key, Location: loc, Message: fmt.Sprintf("included files may only contain instances, found '%s'", key)})
+			}
+			items, pathRe = list, includeInstancePathRe
+		} else {
+			items, single = []interface{}{v}, true
+		}
+	default:
+		return fmt.Errorf("%s: expected an instance object or an array of instances", file)
+	}
+
+	instances, _ := tree["instance"].([]interface{})
+	offset := len(instances)
+	tree["instance"] = append(instances, items...)
+
+	// 将被包含文件中的位置映射到合并后的字段路径
+	for path, loc := range flocs {
+		var index int
+		var rest string
+		if single {
+			if path != "" && !strings.HasPrefix(path, "[") {
+				path = "." + path
+			}
+			rest = path
+		} else {
+			m := pathRe.FindStringSubmatch(path)
+			if m == nil {
+				continue
+			}
+			index, _ = strconv.Atoi(m[1])
+			rest = m[2]
+		}
+		locs[fmt.Sprintf("instance[%d]%s", offset+index, rest)] = loc
+	}
+	for i := range items {
+		path := fmt.Sprintf("instance[%d]", offset+i)
+		if _, ok := locs[path]; !ok {
+			locs[path] = file
+		}
+	}
+	return nil
+}
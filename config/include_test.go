@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeTestFile 写入文件，按需创建目录
+func writeTestFile(t *testing.T, path string, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadIncludesGlobDir(t *testing.T) {
+	root := t.TempDir()
+	mainFile := filepath.Join(root, "config.json")
+	writeTestFile(t, mainFile, "{}")
+	writeTestFile(t, filepath.Join(root, "users", "alice", "gzgspd.json"), `{"name": "alice", "username": "13412345678", "password": "123456"}`)
+	writeTestFile(t, filepath.Join(root, "users", "bob", "other.json"), `{}`)
+
+	tree := map[string]interface{}{"include": []interface{}{"users/*/gzgspd.json"}}
+	var errs ValidationError
+	files, dirs, err := readIncludes(tree, mainFile, locations{}, &errs)
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("readIncludes() error = %v, %v", err, errs)
+	}
+	if want := []string{filepath.Join(root, "users", "alice", "gzgspd.json")}; !slices.Equal(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	// 监视 users 以发现新目录，以及已匹配的目录，不监视带通配符的路径
+	want := []string{
+		filepath.Join(root, "users"),
+		filepath.Join(root, "users", "alice"),
+		filepath.Join(root, "users", "bob"),
+	}
+	if !slices.Equal(dirs, want) {
+		t.Errorf("dirs = %v, want %v", dirs, want)
+	}
+	if instances, _ := tree["instance"].([]interface{}); len(instances) != 1 {
+		t.Errorf("instances = %v, want alice only", tree["instance"])
+	}
+}
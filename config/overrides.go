@@ -0,0 +1,220 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix 覆盖配置字段的环境变量前缀
+// 例如 GZGSPD_LOG_LEVEL=-4、GZGSPD_INSTANCE_0_USERNAME=13412345678、GZGSPD_INSTANCE_0_MACVLAN_PARENT=eth0
+const EnvPrefix = "GZGSPD_"
+
+// 不属于配置字段的环境变量
+var envIgnored = map[string]bool{
+	"CONFIG_FILE": true,
+}
+
+// override 单个字段的覆盖值
+type override struct {
+	source string
+	path   []string
+	value  string
+}
+
+var setPathRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// envOverrides 从环境变量中读取覆盖值
+// 变量名去掉前缀后按 _ 切分，依据配置结构匹配字段名（字段名本身可含 _）与数组下标
+// 无法对应字段的变量可能属于其他程序或旧版本，警告后忽略
+func envOverrides(environ []string) []override {
+	var overrides []override
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(name, EnvPrefix)
+		if envIgnored[key] {
+			continue
+		}
+
+		path, err := envPath(reflect.TypeOf(Config{}), strings.Split(strings.ToLower(key), "_"))
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Ignoring environment variable %s: %v", name, err))
+			continue
+		}
+		overrides = append(overrides, override{source: "environment variable " + name, path: path, value: value})
+	}
+	return overrides
+}
+
+// envPath 将环境变量名的片段匹配为字段路径
+func envPath(t reflect.Type, tokens []string) ([]string, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		// 优先匹配最长的字段名，如 keep_alive_link 优先于 keep_alive
+		for n := len(tokens); n > 0; n-- {
+			f, ok := fields[strings.Join(tokens[:n], "_")]
+			if !ok {
+				continue
+			}
+			rest, err := envPath(f.Type, tokens[n:])
+			if err != nil {
+				continue
+			}
+			return append([]string{strings.Join(tokens[:n], "_")}, rest...), nil
+		}
+		return nil, fmt.Errorf("unknown field '%s'", strings.Join(tokens, "_"))
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Struct {
+			if _, err := strconv.Atoi(tokens[0]); err != nil {
+				return nil, fmt.Errorf("'%s' is not an index", tokens[0])
+			}
+			rest, err := envPath(t.Elem(), tokens[1:])
+			if err != nil {
+				return nil, err
+			}
+			return append([]string{tokens[0]}, rest...), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown field '%s'", strings.Join(tokens, "_"))
+}
+
+// parseSet 解析 --set 参数，格式为 path=value，如 instance[0].keep_alive=10
+func parseSet(s string) (override, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return override{}, fmt.Errorf("--set %s: expected path=value", s)
+	}
+
+	var path []string
+	for _, part := range strings.Split(strings.TrimSpace(name), ".") {
+		field, rest, _ := strings.Cut(part, "[")
+		if !setPathRe.MatchString(field) {
+			return override{}, fmt.Errorf("--set %s: invalid path", s)
+		}
+		path = append(path, field)
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			if _, err := strconv.Atoi(index); !ok || err != nil {
+				return override{}, fmt.Errorf("--set %s: invalid index", s)
+			}
+			path = append(path, index)
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	return override{source: "--set " + name, path: path, value: value}, nil
+}
+
+// applyOverrides 依次将覆盖值写入配置树，后者优先
+func applyOverrides(tree map[string]interface{}, overrides []override) error {
+	for _, o := range overrides {
+		if _, err := setPath(tree, reflect.TypeOf(Config{}), o.path, o.value); err != nil {
+			return fmt.Errorf("%s: %v", o.source, err)
+		}
+	}
+	return nil
+}
+
+// setPath 按字段路径写入值，node 为 nil 时按类型新建，返回写入后的 node
+func setPath(node interface{}, t reflect.Type, path []string, value string) (interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if len(path) == 0 {
+		return overrideValue(t, value)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		f, ok := jsonFields(t)[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown field '%s'", path[0])
+		}
+		m, _ := node.(map[string]interface{})
+		if m == nil {
+			m = map[string]interface{}{}
+		}
+		v, err := setPath(m[path[0]], f.Type, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		m[path[0]] = v
+		return m, nil
+	case reflect.Slice:
+		index, err := strconv.Atoi(path[0])
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("'%s' is not an index", path[0])
+		}
+		s, _ := node.([]interface{})
+		for len(s) <= index {
+			s = append(s, nil)
+		}
+		v, err := setPath(s[index], t.Elem(), path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		s[index] = v
+		return s, nil
+	}
+	return nil, fmt.Errorf("field '%s' has no sub-fields", path[0])
+}
+
+// overrideValue 将字符串值转换为字段类型对应的值
+// 字符串数组以逗号分隔，对象与数组也可直接使用 JSON
+func overrideValue(t reflect.Type, value string) (interface{}, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Int, reflect.Int64:
+		// 与 JSON 解析结果一致，数字均为 float64
+		n, err := strconv.Atoi(value)
+		return float64(n), err
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	case reflect.Slice:
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			break
+		}
+		if t.Elem().Kind() == reflect.String {
+			values := []interface{}{}
+			for _, v := range strings.Split(value, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					values = append(values, v)
+				}
+			}
+			return values, nil
+		}
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %v", t, err)
+	}
+	return v, nil
+}
+
+// ConfigFileFromEnv 返回环境变量指定的配置文件路径
+// 兼容旧的拼写 GSGZPD_CONFIG_FILE
+func ConfigFileFromEnv() string {
+	for _, name := range []string{EnvPrefix + "CONFIG_FILE", "GSGZPD_CONFIG_FILE"} {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
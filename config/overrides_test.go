@@ -0,0 +1,32 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEnvOverrides(t *testing.T) {
+	overrides := envOverrides([]string{
+		"PATH=/usr/bin",
+		"GZGSPD_CONFIG_FILE=/etc/gzgspd.json",
+		"GZGSPD_LOG_LEVEL=-4",
+		"GZGSPD_INSTANCE_0_KEEP_ALIVE_LINK=http://1.1.1.1",
+		"GZGSPD_INSTANCE_1_MACVLAN_PARENT=eth0",
+		// 未知的变量被忽略
+		"GZGSPD_VERSION=1.2",
+		"GZGSPD_INSTANCE_X_USERNAME=13412345678",
+	})
+	want := [][]string{
+		{"log_level"},
+		{"instance", "0", "keep_alive_link"},
+		{"instance", "1", "macvlan", "parent"},
+	}
+	if len(overrides) != len(want) {
+		t.Fatalf("envOverrides() = %+v, want %d overrides", overrides, len(want))
+	}
+	for i := range want {
+		if !slices.Equal(overrides[i].path, want[i]) {
+			t.Errorf("override %d path = %v, want %v", i, overrides[i].path, want[i])
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// 字段说明，以 json 字段路径索引，数组元素不带下标
+var schemaDescriptions = map[string]string{
+	"log_level":                         "Log level (https://go.dev/src/log/slog/level.go)",
+	"log_path":                          "Log path",
+	"state_dir":                         "Directory for saving session state after each login (Empty: don't save)",
+	"site_dir":                          "Directory of user site profiles (*.json) (Empty: built-in profiles only)",
+	"include":                           "Files, globs or directories with more instances, relative to this file",
+	"defaults":                          "Values inherited by every instance unless the instance sets them",
+	"instance":                          "Instances",
+	"instance.name":                     "Unique instance name used in logs, status and state files (Empty: username@interface)",
+	"instance.username":                 "User name",
+	"instance.password":                 "Password",
+	"instance.interface":                "Network interface name or IP for sending HTTP data (Empty: automatically detect)",
+	"instance.user_agent":               "User agent for sending HTTP data",
+	"instance.keep_alive":               "Interval for sending keep-alive",
+	"instance.keep_alive_link":          "keep-alive link (Empty: \"http://3.3.3.3\")",
+	"instance.retry_max":                "Max retries. If exceeded, wait 10 minutes.",
+	"instance.retry_time":               "Retry interval",
+	"instance.dns":                      "DNS servers (IP or IP:port) used to resolve portal hosts through this interface",
+	"instance.netns":                    "Linux network namespace name or path to run this instance in",
+	"instance.macvlan":                  "Create a macvlan sub-interface for this instance",
+	"instance.macvlan.parent":           "Parent interface",
+	"instance.macvlan.name":             "Sub-interface name (Empty: generated)",
+	"instance.macvlan.mac":              "MAC address (Empty: instance mac, or generated)",
+	"instance.macvlan.mode":             "macvlan mode (Empty: bridge)",
+	"instance.macvlan.dhcp":             "How to get an address (Empty: builtin)",
+	"instance.mac":                      "MAC address sent to the portal",
+	"instance.set_link_mac":             "Linux only: set mac on interface before login and restore it on exit",
+	"instance.site":                     "Site profile name (Empty: \"gzgs\")",
+	"instance.provider":                 "Portal protocol (Empty: telecom)",
+	"instance.ruijie":                   "Ruijie ePortal options",
+	"instance.ruijie.url":               "Portal base URL used to log out or check status before the first redirect (Empty: from redirect)",
+	"instance.ruijie.service":           "Service name chosen on the login page (Empty: default service)",
+	"instance.srun":                     "Srun portal options",
+	"instance.srun.url":                 "Portal base URL; when set, online status is checked with rad_user_info (Empty: from redirect)",
+	"instance.srun.ac_id":               "ac_id of the access point (Empty: from redirect, or 1)",
+	"instance.drcom":                    "Dr.COM portal options",
+	"instance.drcom.url":                "Portal base URL; when set, online status is checked before the keep-alive redirect (Empty: from redirect)",
+	"instance.drcom.variant":            "Portal variant: eportal (JSONP interface) or legacy (0.htm form) (Empty: eportal)",
+	"instance.drcom.port":               "Port of the eportal interface (Empty: 801)",
+	"instance.form":                     "Rules for a generic HTML form login; values are Go templates with .Username, .Password, .IP, .MAC, .URL and .Query",
+	"instance.form.url":                 "Login page (Empty: redirect of keep_alive_link)",
+	"instance.form.portal_match":        "Regex the redirect link must match to count as the portal (Empty: any redirect)",
+	"instance.form.form":                "Form to fill: #id, index or name (Empty: first form with a password field)",
+	"instance.form.action":              "Submit URL instead of the form action",
+	"instance.form.method":              "Submit method instead of the form method",
+	"instance.form.fields":              "Fields to fill or add, after the form's own values",
+	"instance.form.fields.name":         "Field name",
+	"instance.form.fields.value":        "Field value template",
+	"instance.form.cookies":             "Cookies set before opening the login page",
+	"instance.form.cookies.name":        "Cookie name",
+	"instance.form.cookies.value":       "Cookie value template",
+	"instance.form.success":             "Regex the login response must match (Empty: check the keep-alive redirect again)",
+	"instance.form.success_json":        "JSONPath that must be set in a JSON or JSONP login response, e.g. $.result",
+	"instance.form.success_value":       "Value expected at success_json (Empty: any value but null, false, 0 or \"\")",
+	"instance.form.logout_url":          "URL opened to log out (Empty: logout not supported)",
+	"instance.proxy":                    "Proxy for portal and keep-alive requests: http://, https://, socks5:// or socks5h:// URL, reached from the instance's address (Empty: direct)",
+	"instance.tls":                      "TLS options for https portal connections (null: site tls, or system defaults)",
+	"instance.tls.ca_file":              "PEM file of CA certificates trusted instead of the system ones",
+	"instance.tls.pin_sha256":           "Base64 SHA-256 hashes of accepted public keys (SPKI); one must be the server's key or in its verified chain",
+	"instance.tls.insecure_skip_verify": "Accept any certificate; the password can then be intercepted (Not recommended)",
+	"instance.tls.min_version":          "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3 (Empty: 1.2)",
+}
+
+// 字段可选值
+var schemaEnums = map[string][]string{
+	"instance.macvlan.mode":    {"", "bridge", "private", "vepa", "passthru", "source"},
+	"instance.macvlan.dhcp":    {"", "builtin", "none", "udhcpc", "dhclient"},
+	"instance.provider":        {"", "telecom", "ruijie", "srun", "drcom", "form"},
+	"instance.drcom.variant":   {"", "eportal", "legacy"},
+	"instance.form.method":     {"", "GET", "POST", "get", "post"},
+	"instance.tls.min_version": {"", "1.0", "1.1", "1.2", "1.3"},
+}
+
+// 数值下限
+var schemaMinimum = map[string]int{
+	"instance.keep_alive": 1,
+	"instance.retry_max":  0,
+	"instance.retry_time": 1,
+	"instance.drcom.port": 0,
+}
+
+// 必填字段
+var schemaRequired = map[string][]string{
+	"":                      {"instance"},
+	"instance":              {"username", "password", "keep_alive", "retry_time"},
+	"instance.macvlan":      {"parent"},
+	"instance.form.fields":  {"name"},
+	"instance.form.cookies": {"name"},
+}
+
+// Schema 返回配置文件的 JSON Schema，供编辑器补全与校验
+func Schema() map[string]interface{} {
+	schema := schemaOf(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "gzgspd configuration"
+	return schema
+}
+
+func schemaOf(t reflect.Type, path string) map[string]interface{} {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	s := map[string]interface{}{}
+	switch t.Kind() {
+	case reflect.Struct:
+		props := map[string]interface{}{}
+		for name, f := range jsonFields(t) {
+			child := name
+			if path != "" {
+				child = path + "." + name
+			}
+			props[name] = schemaOf(f.Type, child)
+		}
+		s["type"] = "object"
+		s["properties"] = props
+		s["additionalProperties"] = false
+		if required, ok := schemaRequired[path]; ok {
+			s["required"] = required
+		}
+	case reflect.Slice:
+		items := schemaOf(t.Elem(), path)
+		delete(items, "description")
+		s["type"] = "array"
+		s["items"] = items
+	case reflect.String:
+		s["type"] = "string"
+	case reflect.Bool:
+		s["type"] = "boolean"
+	case reflect.Int, reflect.Int64:
+		s["type"] = "integer"
+	}
+
+	if nullable {
+		s["type"] = []interface{}{s["type"], "null"}
+	}
+	// defaults 下的字段与实例字段说明相同
+	if rest, ok := strings.CutPrefix(path, "defaults."); ok {
+		path = "instance." + rest
+	}
+	if desc, ok := schemaDescriptions[path]; ok {
+		s["description"] = desc
+	}
+	if enum, ok := schemaEnums[path]; ok {
+		s["enum"] = enum
+	}
+	if min, ok := schemaMinimum[path]; ok {
+		s["minimum"] = min
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSite 未指定 site 时使用的站点
+const DefaultSite = "gzgs"
+
+// SiteProfile 站点配置，描述同一门户产品在不同校园的差异
+// 登出时若没有保存的会话信息，使用其中的值
+type SiteProfile struct {
+	Name           string     `json:"name"`
+	Scheme         string     `json:"scheme"`
+	Host           string     `json:"host"`
+	WlanacIp       string     `json:"wlanac_ip"`
+	Wlanacname     string     `json:"wlanacname"`
+	Version        int        `json:"version"`
+	GroupID        int        `json:"group_id"`
+	UserSuffix     string     `json:"user_suffix"`
+	PortalType     string     `json:"portal_type"`
+	PortalKeywords []string   `json:"portal_keywords"`
+	TLS            *ConfigTLS `json:"tls"`
+}
+
+// BuiltinSites 内置站点
+var BuiltinSites = map[string]SiteProfile{
+	// 广州工商学院 电信 ePortal
+	"gzgs": {
+		Name:           "gzgs",
+		Scheme:         "https",
+		Host:           "10.20.16.5",
+		WlanacIp:       "10.20.16.2",
+		Wlanacname:     "NFV-BASE-01",
+		Version:        4,
+		GroupID:        19,
+		UserSuffix:     "@SSGSXY",
+		PortalType:     "0",
+		PortalKeywords: []string{"portalScript.do", "portal.do"},
+	},
+}
+
+// Validate 校验站点配置
+func (p *SiteProfile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("site name cannot be empty")
+	}
+	if p.Scheme != "http" && p.Scheme != "https" {
+		return fmt.Errorf("site '%s' scheme must be http or https", p.Name)
+	}
+	if p.Host == "" {
+		return fmt.Errorf("site '%s' host cannot be empty", p.Name)
+	}
+	if len(p.PortalKeywords) == 0 {
+		return fmt.Errorf("site '%s' requires at least one portal keyword", p.Name)
+	}
+	if p.TLS != nil {
+		var errs ValidationError
+		p.TLS.validate(&errs, "tls", fmt.Sprintf("site '%s'", p.Name))
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+	return nil
+}
+
+// LoadSites 读取内置站点与目录中的用户站点文件（*.json，每个文件一个站点）
+// 用户站点未填写 name 时使用文件名，与内置站点同名时覆盖内置站点
+func LoadSites(dir string) (map[string]SiteProfile, error) {
+	sites := make(map[string]SiteProfile, len(BuiltinSites))
+	for name, p := range BuiltinSites {
+		sites[name] = p
+	}
+	if dir == "" {
+		return sites, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var p SiteProfile
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("site file %s: %v", file, err)
+		}
+		if p.Name == "" {
+			p.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("site file %s: %v", file, err)
+		}
+		sites[p.Name] = p
+	}
+	return sites, nil
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// uciSection UCI 配置中的一节
+type uciSection struct {
+	Type    string
+	Name    string
+	Options map[string][]string
+	Order   []string
+}
+
+// parseUCI 将 UCI 配置（如 /etc/config/gzgspd）转换为与 JSON 配置相同结构的数据
+//
+//	config gzgspd 'main'
+//		option log_level '0'
+//
+//	config instance
+//		option enabled '1'
+//		option username '13312345678'
+//		option macvlan_parent 'eth0'
+//		list dns '223.5.5.5'
+//
+// gzgspd 节对应顶层字段，每个 instance 节对应一个实例，enabled 为 0 的实例被忽略；
+// macvlan_、ruijie_、srun_、drcom_、form_ 与 tls_ 前缀的选项对应实例中同名的嵌套配置
+func parseUCI(data []byte) (map[string]interface{}, error) {
+	sections, err := parseUCISections(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]interface{}{}
+	instances := []interface{}{}
+	for _, sec := range sections {
+		switch sec.Type {
+		case "gzgspd":
+			if err := uciApply(tree, reflect.TypeOf(Config{}), sec); err != nil {
+				return nil, err
+			}
+		case "instance":
+			if v, ok := sec.Options["enabled"]; ok && !uciBool(v[len(v)-1]) {
+				continue
+			}
+			delete(sec.Options, "enabled")
+
+			// 具名节的名称作为实例名称
+			inst := map[string]interface{}{}
+			if sec.Name != "" {
+				inst["name"] = sec.Name
+			}
+			if err := uciApply(inst, reflect.TypeOf(ConfigInstance{}), sec); err != nil {
+				return nil, err
+			}
+			instances = append(instances, inst)
+		default:
+			return nil, fmt.Errorf("unknown uci section type '%s'", sec.Type)
+		}
+	}
+	tree["instance"] = instances
+	return tree, nil
+}
+
+// uciApply 将节中的选项按结构体字段类型转换后写入 tree
+// 结构体指针字段（如 macvlan）由带 <字段名>_ 前缀的选项设置，写入 tree 中对应的子节点
+func uciApply(tree map[string]interface{}, t reflect.Type, sec *uciSection) error {
+	fields := jsonFields(t)
+
+	for _, key := range sec.Order {
+		values, ok := sec.Options[key]
+		if !ok {
+			continue
+		}
+
+		target, name := tree, key
+		f, ok := fields[key]
+		if !ok {
+			target, name, f, ok = uciNested(tree, fields, key)
+		}
+		if !ok {
+			return fmt.Errorf("unknown uci option '%s' in %s section", key, sec.Type)
+		}
+		if isNestedStruct(f.Type) {
+			return fmt.Errorf("uci option '%s': use '%s_<field>' options instead", key, key)
+		}
+		v, err := uciValue(f.Type, values)
+		if err != nil {
+			return fmt.Errorf("uci option '%s': %v", key, err)
+		}
+		target[name] = v
+	}
+	return nil
+}
+
+// uciNested 查找带嵌套配置前缀的选项，如 macvlan_parent 对应 macvlan 中的 parent
+// 返回写入的子节点、字段名与字段
+func uciNested(tree map[string]interface{}, fields map[string]reflect.StructField, key string) (map[string]interface{}, string, reflect.StructField, bool) {
+	for prefix, pf := range fields {
+		name, ok := strings.CutPrefix(key, prefix+"_")
+		if !ok || !isNestedStruct(pf.Type) {
+			continue
+		}
+		f, ok := jsonFields(pf.Type.Elem())[name]
+		if !ok {
+			continue
+		}
+		nested, _ := tree[prefix].(map[string]interface{})
+		if nested == nil {
+			nested = map[string]interface{}{}
+			tree[prefix] = nested
+		}
+		return nested, name, f, true
+	}
+	return nil, "", reflect.StructField{}, false
+}
+
+// isNestedStruct 是否为结构体指针字段
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct
+}
+
+// uciValue 将 UCI 字符串值转换为字段类型对应的值
+// 表单字段与 Cookie 写作 name=value
+func uciValue(t reflect.Type, values []string) (interface{}, error) {
+	last := values[len(values)-1]
+	switch t.Kind() {
+	case reflect.Slice:
+		if t.Elem() == reflect.TypeOf(ConfigFormField{}) {
+			var fields []interface{}
+			for _, v := range values {
+				name, value, ok := strings.Cut(v, "=")
+				if !ok {
+					return nil, fmt.Errorf("'%s' must be name=value", v)
+				}
+				fields = append(fields, map[string]interface{}{"name": name, "value": value})
+			}
+			return fields, nil
+		}
+		if t.Elem().Kind() == reflect.String {
+			return values, nil
+		}
+	case reflect.Int, reflect.Int64:
+		return strconv.Atoi(last)
+	case reflect.Bool:
+		return uciBool(last), nil
+	case reflect.String:
+		return last, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// uciBool 按 UCI 习惯解析布尔值
+func uciBool(v string) bool {
+	switch strings.ToLower(v) {
+	case "1", "true", "yes", "on", "enabled":
+		return true
+	}
+	return false
+}
+
+// parseUCISections 解析 UCI 文本
+func parseUCISections(data []byte) ([]*uciSection, error) {
+	var sections []*uciSection
+	var cur *uciSection
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		tokens, err := uciTokens(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+
+		switch tokens[0] {
+		case "package":
+		case "config":
+			if len(tokens) < 2 || len(tokens) > 3 {
+				return nil, fmt.Errorf("line %d: expected 'config <type> [name]'", line)
+			}
+			cur = &uciSection{Type: tokens[1], Options: map[string][]string{}}
+			if len(tokens) == 3 {
+				cur.Name = tokens[2]
+			}
+			sections = append(sections, cur)
+		case "option", "list":
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: %s outside of a config section", line, tokens[0])
+			}
+			if len(tokens) != 3 {
+				return nil, fmt.Errorf("line %d: expected '%s <name> <value>'", line, tokens[0])
+			}
+			name := tokens[1]
+			if _, ok := cur.Options[name]; !ok {
+				cur.Order = append(cur.Order, name)
+			}
+			if tokens[0] == "option" {
+				cur.Options[name] = []string{tokens[2]}
+			} else {
+				cur.Options[name] = append(cur.Options[name], tokens[2])
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword '%s'", line, tokens[0])
+		}
+	}
+	return sections, scanner.Err()
+}
+
+// uciTokens 按 shell 规则拆分一行，支持单双引号、反斜杠转义与 # 注释
+func uciTokens(line string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inToken := false
+	escaped := false
+	var quote rune
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inToken = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inToken = true
+		case r == '#' && !inToken:
+			return tokens, nil
+		case r == ' ' || r == '\t':
+			if inToken {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+				inToken = false
+			}
+		default:
+			cur.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inToken {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
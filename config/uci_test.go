@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseUCINested(t *testing.T) {
+	data := []byte(`
+config gzgspd 'main'
+	option log_level '0'
+
+config instance 'campus'
+	option username '2023001'
+	option password 'secret'
+	option provider 'form'
+	option macvlan_parent 'eth0'
+	option ruijie_service 'internet'
+	option srun_ac_id '5'
+	option drcom_variant 'legacy'
+	option drcom_port '8080'
+	option form_url 'http://10.0.0.1/login'
+	list form_fields 'user={{.Username}}'
+	list form_fields 'pass={{.Password}}'
+	list form_cookies 'lang=zh=CN'
+	list tls_pin_sha256 'mJpKhXa8QgSMS1e2dKKaCuUz5vccqzJtxGmqlJVrfd8='
+	option tls_min_version '1.2'
+	option tls_insecure_skip_verify '1'
+
+config instance 'off'
+	option enabled '0'
+	option tls 'x'
+`)
+	tree, err := parseUCI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Instance) != 1 {
+		t.Fatalf("got %d instances, want 1", len(cfg.Instance))
+	}
+	inst := cfg.Instance[0]
+	if inst.Name != "campus" || inst.Macvlan == nil || inst.Macvlan.Parent != "eth0" {
+		t.Errorf("instance = %+v", inst)
+	}
+	if inst.Ruijie == nil || inst.Ruijie.Service != "internet" {
+		t.Errorf("ruijie = %+v", inst.Ruijie)
+	}
+	if inst.Srun == nil || inst.Srun.AcID != "5" {
+		t.Errorf("srun = %+v", inst.Srun)
+	}
+	if inst.Drcom == nil || inst.Drcom.Variant != "legacy" || inst.Drcom.Port != 8080 {
+		t.Errorf("drcom = %+v", inst.Drcom)
+	}
+	wantFields := []ConfigFormField{{"user", "{{.Username}}"}, {"pass", "{{.Password}}"}}
+	if inst.Form == nil || inst.Form.URL != "http://10.0.0.1/login" || !slices.Equal(inst.Form.Fields, wantFields) {
+		t.Errorf("form = %+v", inst.Form)
+	} else if !slices.Equal(inst.Form.Cookies, []ConfigFormField{{"lang", "zh=CN"}}) {
+		t.Errorf("form cookies = %+v", inst.Form.Cookies)
+	}
+	if inst.TLS == nil || len(inst.TLS.PinSHA256) != 1 || inst.TLS.MinVersion != "1.2" || !inst.TLS.InsecureSkipVerify {
+		t.Errorf("tls = %+v", inst.TLS)
+	}
+}
+
+func TestParseUCIErrors(t *testing.T) {
+	tests := []struct {
+		option string
+		want   string
+	}{
+		{"option tls '1'", "use 'tls_<field>' options instead"},
+		{"option tls_unknown '1'", "unknown uci option 'tls_unknown'"},
+		{"list form_fields 'novalue'", "must be name=value"},
+	}
+	for _, tt := range tests {
+		_, err := parseUCI([]byte("config instance\n\t" + tt.option + "\n"))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseUCI(%q) error = %v, want %q", tt.option, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError 单个字段的配置错误
+type FieldError struct {
+	Path     string // 字段路径，如 instance[0].keep_alive
+	Location string // 出错位置，如 config.json:12:7 或覆盖该字段的环境变量，未知时为空
+	Message  string
+}
+
+func (e FieldError) Error() string {
+	if e.Location == "" {
+		return e.Message
+	}
+	return e.Location + ": " + e.Message
+}
+
+// ValidationError 汇总的配置错误，每行一个
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	lines := make([]string, len(e))
+	for i, fe := range e {
+		lines[i] = fe.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *ValidationError) add(path, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// locations 字段路径到其在配置来源中位置的映射
+type locations map[string]string
+
+// locate 为错误填写位置，字段本身没有位置时（如缺少的字段）使用最近的上级
+// 已有位置的错误（如重复的键）保持不变
+func (l locations) locate(errs ValidationError) {
+	for i := range errs {
+		if errs[i].Location != "" {
+			continue
+		}
+		for path := errs[i].Path; ; path = parentPath(path) {
+			if loc, ok := l[path]; ok {
+				errs[i].Location = loc
+				break
+			}
+			if path == "" {
+				break
+			}
+		}
+	}
+}
+
+// override 将被覆盖的字段及新建的上级字段指向覆盖来源
+func (l locations) override(o override) {
+	for i := len(o.path); i > 0; i-- {
+		path := joinPath(o.path[:i])
+		if _, ok := l[path]; ok && i < len(o.path) {
+			break
+		}
+		l[path] = o.source
+	}
+}
+
+// parentPath 返回上一级字段路径，instance[0].dns[1] -> instance[0].dns -> instance[0] -> instance -> ""
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		return path[:strings.LastIndex(path, "[")]
+	}
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// joinPath 将字段路径片段拼接为 instance[0].keep_alive 形式
+func joinPath(path []string) string {
+	var b strings.Builder
+	for _, p := range path {
+		if _, err := strconv.Atoi(p); err == nil {
+			b.WriteString("[" + p + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// jsonLocations 记录 JSON 中每个字段的行列位置，并检查重复的键
+// data 需为 StripJSONC 的结果，与原文件位置一致
+func jsonLocations(file string, data []byte) (locations, ValidationError) {
+	w := &jsonWalker{
+		file: file,
+		data: data,
+		dec:  json.NewDecoder(bytes.NewReader(data)),
+		locs: locations{},
+	}
+	// 语法错误由之后的解析报告
+	w.value("")
+	return w.locs, w.errs
+}
+
+type jsonWalker struct {
+	file string
+	data []byte
+	dec  *json.Decoder
+	locs locations
+	errs ValidationError
+}
+
+// pos 返回下一个 token 的位置
+func (w *jsonWalker) pos() string {
+	off := int(w.dec.InputOffset())
+	for off < len(w.data) && strings.IndexByte(" \t\r\n,:", w.data[off]) >= 0 {
+		off++
+	}
+	line := 1 + bytes.Count(w.data[:off], []byte("\n"))
+	col := off - bytes.LastIndexByte(w.data[:off], '\n')
+	return fmt.Sprintf("%s:%d:%d", w.file, line, col)
+}
+
+func (w *jsonWalker) value(path string) error {
+	if _, ok := w.locs[path]; !ok {
+		w.locs[path] = w.pos()
+	}
+	tok, err := w.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		seen := make(map[string]bool)
+		for w.dec.More() {
+			loc := w.pos()
+			tok, err := w.dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			child := joinPath([]string{path, key})
+			if seen[key] {
+				w.errs = append(w.errs, FieldError{Path: child, Location: loc, Message: fmt.Sprintf("duplicate key '%s'", child)})
+			} else {
+				w.locs[child] = loc
+			}
+			seen[key] = true
+			if err := w.value(child); err != nil {
+				return err
+			}
+		}
+		_, err = w.dec.Token()
+	case json.Delim('['):
+		for i := 0; w.dec.More(); i++ {
+			if err := w.value(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		_, err = w.dec.Token()
+	}
+	return err
+}
+
+// yamlLocations 记录 YAML 中每个字段的行列位置
+func yamlLocations(file string, data []byte) locations {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) == 0 {
+		return locations{}
+	}
+
+	locs := locations{}
+	var walk func(n *yaml.Node, path string)
+	walk = func(n *yaml.Node, path string) {
+		if _, ok := locs[path]; !ok {
+			locs[path] = fmt.Sprintf("%s:%d:%d", file, n.Line, n.Column)
+		}
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key := n.Content[i]
+				child := joinPath([]string{path, key.Value})
+				locs[child] = fmt.Sprintf("%s:%d:%d", file, key.Line, key.Column)
+				walk(n.Content[i+1], child)
+			}
+		case yaml.SequenceNode:
+			for i, item := range n.Content {
+				walk(item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+	walk(root.Content[0], "")
+	return locs
+}
+
+// checkTree 检查配置树中的未知字段与类型不符的值
+func checkTree(node interface{}, t reflect.Type, path string, errs *ValidationError) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node == nil {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			errs.add(path, "%s must be an object", path)
+			return
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := joinPath([]string{path, k})
+			f, ok := fields[k]
+			if !ok {
+				errs.add(child, "unknown field '%s'", child)
+				continue
+			}
+			checkTree(m[k], f.Type, child, errs)
+		}
+	case reflect.Slice:
+		s, ok := node.([]interface{})
+		if !ok {
+			errs.add(path, "%s must be an array", path)
+			return
+		}
+		for i, v := range s {
+			checkTree(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.String:
+		if _, ok := node.(string); !ok {
+			errs.add(path, "%s must be a string", path)
+		}
+	case reflect.Bool:
+		if _, ok := node.(bool); !ok {
+			errs.add(path, "%s must be a boolean", path)
+		}
+	case reflect.Int, reflect.Int64:
+		if f, ok := node.(float64); !ok || f != float64(int64(f)) {
+			errs.add(path, "%s must be an integer", path)
+		}
+	}
+}
+
+// syntaxLocation 将 JSON 语法错误的偏移转换为行列位置
+func syntaxLocation(file string, data []byte, err error) error {
+	serr, ok := err.(*json.SyntaxError)
+	if !ok || serr.Offset > int64(len(data)) {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	off := int(serr.Offset)
+	line := 1 + bytes.Count(data[:off], []byte("\n"))
+	col := off - bytes.LastIndexByte(data[:off], '\n')
+	return fmt.Errorf("%s:%d:%d: %v", file, line, col, err)
+}
+
+// hostHasInterface 检查本机是否存在该名称或 IP 的接口
+func hostHasInterface(nameOrIP string) bool {
+	ip := net.ParseIP(nameOrIP)
+	if ip == nil {
+		_, err := net.InterfaceByName(nameOrIP)
+		return err == nil
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validHTTPURL 检查是否为带主机名的 http 或 https 链接
+func validHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validProxyURL 代理须为 http、https 或 socks5 地址，socks5h 由代理解析域名
+func validProxyURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return true
+	}
+	return false
+}
+
+// redactURL 隐藏 URL 中的密码，无法解析时原样返回
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 文件变化后等待的时间，合并编辑器保存时的多次写入
+const watchDebounce = 500 * time.Millisecond
+
+// Watcher 监视配置文件（含被包含的文件）与 include 目录的变化
+// Linux 上使用 inotify 监视文件所在目录，以兼容先写临时文件再替换的保存方式；其他系统定时轮询
+type Watcher struct {
+	files  map[string]bool
+	dirs   map[string]bool
+	events chan struct{}
+	quit   chan struct{}
+
+	lock  sync.Mutex
+	timer *time.Timer
+
+	sys watcherSys
+}
+
+// NewWatcher 开始监视文件，以及目录中文件的增删改
+func NewWatcher(files []string, dirs []string) (*Watcher, error) {
+	w := &Watcher{
+		files:  make(map[string]bool, len(files)),
+		dirs:   make(map[string]bool, len(dirs)),
+		events: make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+	}
+	for _, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return nil, err
+		}
+		w.files[abs] = true
+	}
+	for _, d := range dirs {
+		abs, err := filepath.Abs(d)
+		if err != nil {
+			return nil, err
+		}
+		w.dirs[abs] = true
+	}
+	if err := w.start(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Events 返回文件变化通知，连续的变化只通知一次
+func (w *Watcher) Events() <-chan struct{} {
+	return w.events
+}
+
+// Close 停止监视
+func (w *Watcher) Close() {
+	close(w.quit)
+	w.stop()
+
+	w.lock.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.lock.Unlock()
+}
+
+// changed 在最后一次变化 watchDebounce 后发出通知
+func (w *Watcher) changed() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.timer != nil {
+		w.timer.Reset(watchDebounce)
+		return
+	}
+	w.timer = time.AfterFunc(watchDebounce, func() {
+		select {
+		case w.events <- struct{}{}:
+		default:
+		}
+	})
+}
@@ -0,0 +1,173 @@
+//go:build linux
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+type watcherSys struct {
+	fd   int
+	file *os.File
+	dirs map[int32]string
+	// 尚不存在的目录（如 include 的目录）改为监视最近的已存在上级，记录上级路径与等待的目录
+	parents map[int32]string
+	pending map[int32][]string
+}
+
+const watchMask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_CREATE | unix.IN_DELETE
+
+func (w *Watcher) start() error {
+	// 非阻塞的 fd 由运行时轮询，Close 时读取立即返回
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	w.sys.fd = fd
+	w.sys.file = os.NewFile(uintptr(fd), "inotify")
+	w.sys.dirs = make(map[int32]string)
+	w.sys.parents = make(map[int32]string)
+	w.sys.pending = make(map[int32][]string)
+
+	dirs := make(map[string]bool, len(w.files)+len(w.dirs))
+	for file := range w.files {
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range w.dirs {
+		dirs[dir] = true
+	}
+	for dir := range dirs {
+		if _, err := w.watchDir(dir); err != nil {
+			w.sys.file.Close()
+			return err
+		}
+	}
+
+	go w.read()
+	return nil
+}
+
+func (w *Watcher) stop() {
+	w.sys.file.Close()
+}
+
+// watchDir 监视目录，目录尚不存在时监视最近的已存在上级，等待其被创建
+// 返回目录本身是否已被监视
+func (w *Watcher) watchDir(target string) (bool, error) {
+	dir := target
+	for {
+		wd, err := unix.InotifyAddWatch(w.sys.fd, dir, watchMask)
+		if err == unix.ENOENT || err == unix.ENOTDIR {
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				return false, nil
+			}
+			dir = parent
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if dir == target {
+			w.sys.dirs[int32(wd)] = dir
+			return true, nil
+		}
+
+		w.sys.parents[int32(wd)] = dir
+		if !slices.Contains(w.sys.pending[int32(wd)], target) {
+			w.sys.pending[int32(wd)] = append(w.sys.pending[int32(wd)], target)
+		}
+		// 开始监视上级前下一级已被创建时，不会再收到其事件，重新查找
+		if _, err := os.Stat(nextDir(dir, target)); err != nil {
+			return false, nil
+		}
+		dir = target
+	}
+}
+
+// nextDir 返回 dir 下通往 target 的一级
+func nextDir(dir, target string) string {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return target
+	}
+	first, _, _ := strings.Cut(rel, string(filepath.Separator))
+	return filepath.Join(dir, first)
+}
+
+// read 读取 inotify 事件，只关注被监视的文件与目录
+func (w *Watcher) read() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.sys.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+unix.SizeofInotifyEvent : off+unix.SizeofInotifyEvent+int(event.Len)]
+			off += unix.SizeofInotifyEvent + int(event.Len)
+
+			name := string(nameBytes)
+			for len(name) > 0 && name[len(name)-1] == 0 {
+				name = name[:len(name)-1]
+			}
+			w.event(event.Wd, event.Mask, name)
+		}
+	}
+}
+
+// event 处理一个 inotify 事件
+func (w *Watcher) event(wd int32, mask uint32, name string) {
+	if dir, ok := w.sys.dirs[wd]; ok {
+		if mask&unix.IN_IGNORED != 0 {
+			// 目录被删除，等待其重新创建
+			delete(w.sys.dirs, wd)
+			w.watchDir(dir)
+			w.changed()
+		} else if w.dirs[dir] || w.files[filepath.Join(dir, name)] {
+			w.changed()
+		}
+	}
+
+	parent, ok := w.sys.parents[wd]
+	if !ok {
+		return
+	}
+	targets := w.sys.pending[wd]
+	if mask&unix.IN_IGNORED != 0 {
+		// 上级也被删除，继续向上等待
+		delete(w.sys.parents, wd)
+		delete(w.sys.pending, wd)
+		for _, target := range targets {
+			w.watchDir(target)
+		}
+		return
+	}
+	if name == "" {
+		return
+	}
+	// 通往等待目录的一级被创建时，监视更近的上级或目录本身
+	created := filepath.Join(parent, name)
+	w.sys.pending[wd] = nil
+	var next []string
+	for _, target := range targets {
+		if nextDir(parent, target) == created {
+			next = append(next, target)
+		} else {
+			w.sys.pending[wd] = append(w.sys.pending[wd], target)
+		}
+	}
+	for _, target := range next {
+		if ok, _ := w.watchDir(target); ok {
+			w.changed()
+		}
+	}
+}
@@ -0,0 +1,100 @@
+//go:build linux
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitEvent 等待一次变化通知
+func waitEvent(t *testing.T, w *Watcher, want bool) {
+	t.Helper()
+	select {
+	case <-w.Events():
+		if !want {
+			t.Fatal("unexpected change notification")
+		}
+	case <-time.After(2 * watchDebounce):
+		if want {
+			t.Fatal("no change notification")
+		}
+	}
+}
+
+func TestWatcherMissingDir(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "config.json")
+	if err := os.WriteFile(file, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "conf.d", "users")
+
+	w, err := NewWatcher([]string{file}, []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// 创建上级目录不是变化
+	if err := os.Mkdir(filepath.Join(root, "conf.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, false)
+
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, true)
+
+	// 之后目录中的文件被监视
+	if err := os.WriteFile(filepath.Join(dir, "alice.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, true)
+
+	// 目录被删除后重新创建
+	if err := os.RemoveAll(filepath.Join(root, "conf.d")); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, true)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, true)
+	if err := os.WriteFile(filepath.Join(dir, "bob.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, true)
+}
+
+func TestWatcherGlobDir(t *testing.T) {
+	root := t.TempDir()
+	mainFile := filepath.Join(root, "config.json")
+	writeTestFile(t, mainFile, "{}")
+	writeTestFile(t, filepath.Join(root, "users", "alice", "gzgspd.json"), "{}")
+
+	tree := map[string]interface{}{"include": []interface{}{"users/*/gzgspd.json"}}
+	var errs ValidationError
+	files, dirs, err := readIncludes(tree, mainFile, locations{}, &errs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWatcher(append([]string{mainFile}, files...), dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// 已匹配的目录中的文件被修改
+	writeTestFile(t, filepath.Join(root, "users", "alice", "gzgspd.json"), `{"name": "alice"}`)
+	waitEvent(t, w, true)
+
+	// 新用户的目录被创建
+	if err := os.Mkdir(filepath.Join(root, "users", "bob"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, true)
+}
@@ -0,0 +1,69 @@
+//go:build !linux
+
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// 轮询间隔
+const watchInterval = 2 * time.Second
+
+type watcherSys struct{}
+
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func (w *Watcher) start() error {
+	go w.poll()
+	return nil
+}
+
+func (w *Watcher) stop() {}
+
+// poll 定时比较文件与目录的大小与修改时间
+func (w *Watcher) poll() {
+	stamps := w.stamps()
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.quit:
+			return
+		case <-ticker.C:
+		}
+
+		now := w.stamps()
+		for file, stamp := range now {
+			if stamps[file] != stamp {
+				w.changed()
+				break
+			}
+		}
+		stamps = now
+	}
+}
+
+func (w *Watcher) stamps() map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(w.files)+len(w.dirs))
+	paths := make([]string, 0, len(w.files)+len(w.dirs))
+	for file := range w.files {
+		paths = append(paths, file)
+	}
+	for dir := range w.dirs {
+		paths = append(paths, dir)
+	}
+	for _, file := range paths {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = fileStamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+		} else {
+			stamps[file] = fileStamp{}
+		}
+	}
+	return stamps
+}
@@ -0,0 +1,201 @@
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/summonhim/gzgspd/portal"
+)
+
+// drcomProvider Dr.COM 网页认证，支持 eportal 与旧版 0.htm 两种页面
+type drcomProvider struct{}
+
+// 门户地址，优先使用登录时保存的地址
+func drcomBaseURL(instance *WorkerInstance) string {
+	if u := instance.Session["url"]; u != "" {
+		return u
+	}
+	if instance.Drcom != nil {
+		return instance.Drcom.URL
+	}
+	return ""
+}
+
+func drcomLegacy(instance *WorkerInstance) bool {
+	return instance.Drcom != nil && instance.Drcom.Variant == "legacy"
+}
+
+// eportal 接口端口
+func drcomPort(instance *WorkerInstance) int {
+	if instance.Drcom != nil && instance.Drcom.Port != 0 {
+		return instance.Drcom.Port
+	}
+	return 801
+}
+
+// 查询在线状态，在线时返回账号
+func drcomOnline(instance *WorkerInstance, baseURL string) (bool, string, error) {
+	if drcomLegacy(instance) {
+		return portal.DrcomLegacyStatus(instance.bind, baseURL, instance.UserAgent)
+	}
+	status, err := portal.DrcomEportalStatus(instance.bind, baseURL, instance.UserAgent)
+	if err != nil {
+		return false, "", err
+	}
+	return status.OK(), status.UID, nil
+}
+
+// 登出时提交的客户端信息
+func drcomClient(instance *WorkerInstance) portal.DrcomClient {
+	client := portal.DrcomClient{
+		IP:     instance.Session["ip"],
+		MAC:    instance.Session["mac"],
+		AcIP:   instance.Session["ac_ip"],
+		AcName: instance.Session["ac_name"],
+	}
+	if client.IP == "" {
+		client.IP = instance.LoginIfIP
+	}
+	if client.MAC == "" {
+		client.MAC = instance.MAC
+	}
+	return client
+}
+
+func (drcomProvider) login(instance *WorkerInstance, statusKey string) bool {
+	baseURL := drcomBaseURL(instance)
+	client := portal.DrcomClient{IP: instance.LoginIfIP, MAC: instance.MAC}
+
+	if baseURL != "" {
+		// 已知门户地址时直接查询在线状态
+		slog.Debug(fmt.Sprintf("[%s] Checking online status.", statusKey))
+		online, uid, err := drcomOnline(instance, baseURL)
+		if err != nil {
+			slog.Debug(fmt.Sprintf("[%s] Failed to query online status: %v", statusKey, err))
+		} else if online {
+			slog.Debug(fmt.Sprintf("[%s] Online as %s.", statusKey, uid))
+			return true
+		}
+	}
+
+	// 检查是否跳转到门户，并从跳转链接中取得门户地址与客户端信息
+	host := ""
+	if baseURL != "" {
+		if u, err := url.Parse(baseURL); err == nil {
+			host = u.Hostname()
+		}
+	}
+	slog.Debug(fmt.Sprintf("[%s] Checking portal if login is required.", statusKey))
+	walk := portal.DrcomPortalChecker(instance.bind, instance.cookieJar(), instance.KAliveLink, host)
+	logPortalWalk(statusKey, walk)
+	needLogin, needLoginUrl := walk.Found(), walk.URL
+	if needLogin {
+		nlu, err := url.Parse(needLoginUrl)
+		if err != nil {
+			setWorkerStatus(statusKey, StateNotLoggedIn)
+			slog.Error(fmt.Sprintf("[%s] Failed to parse redirect login link: %v", statusKey, err))
+			return false
+		}
+		if baseURL == "" {
+			baseURL = nlu.Scheme + "://" + nlu.Host
+		}
+		linkClient := portal.DrcomClientFromLink(nlu)
+		if linkClient.IP != "" {
+			client.IP = linkClient.IP
+		}
+		if linkClient.MAC != "" {
+			checkPortalMAC(instance, statusKey, "redirect", linkClient.MAC)
+			client.MAC = linkClient.MAC
+		}
+		client.AcIP = linkClient.AcIP
+		client.AcName = linkClient.AcName
+	} else if baseURL == "" {
+		return true
+	}
+
+	setWorkerStatus(statusKey, StateLoggingIn)
+	slog.Info(fmt.Sprintf("[%s] Login require.", statusKey))
+
+	// 登录
+	if drcomLegacy(instance) {
+		code, err := portal.DrcomLegacyLogin(instance.bind, baseURL, instance.UserAgent, instance.Username, instance.Password)
+		if err != nil {
+			setWorkerStatus(statusKey, StateNotLoggedIn)
+			slog.Error(fmt.Sprintf("[%s] Login failed: %v", statusKey, err))
+			return false
+		}
+		// 旧版页面没有明确的结果，以在线状态为准
+		online, _, err := portal.DrcomLegacyStatus(instance.bind, baseURL, instance.UserAgent)
+		if err != nil || !online {
+			setWorkerStatus(statusKey, StateNotLoggedIn)
+			if code != "" {
+				slog.Error(fmt.Sprintf("[%s] Login failed: %s", statusKey, portal.DrcomLegacyMessage(code)))
+			} else if err != nil {
+				slog.Error(fmt.Sprintf("[%s] Login failed: %v", statusKey, err))
+			} else {
+				slog.Error(fmt.Sprintf("[%s] Login failed: still offline after login.", statusKey))
+			}
+			return false
+		}
+	} else {
+		loginStat, err := portal.DrcomEportalLogin(instance.bind, baseURL, drcomPort(instance), instance.UserAgent, instance.Username, instance.Password, client)
+		if err != nil {
+			setWorkerStatus(statusKey, StateNotLoggedIn)
+			slog.Error(fmt.Sprintf("[%s] Login failed: %v", statusKey, err))
+			return false
+		}
+		if !loginStat.OK() && !loginStat.AlreadyOnline() {
+			setWorkerStatus(statusKey, StateNotLoggedIn)
+			slog.Error(fmt.Sprintf("[%s] Login failed: %s", statusKey, loginStat.Msg))
+			return false
+		}
+	}
+
+	instance.Session = map[string]string{
+		"url":     baseURL,
+		"ip":      client.IP,
+		"mac":     client.MAC,
+		"ac_ip":   client.AcIP,
+		"ac_name": client.AcName,
+	}
+	instance.saveSession(statusKey)
+	setWorkerStatus(statusKey, StateLoggedIn)
+	slog.Info(fmt.Sprintf("[%s] Login successfully!", statusKey))
+	return true
+}
+
+func (drcomProvider) logout(instance *WorkerInstance, statusKey string) bool {
+	baseURL := drcomBaseURL(instance)
+	if baseURL == "" {
+		slog.Error(fmt.Sprintf("[%s] Logout failed: no saved session or drcom url to log out.", statusKey))
+		return false
+	}
+
+	if drcomLegacy(instance) {
+		if err := portal.DrcomLegacyLogout(instance.bind, baseURL, instance.UserAgent); err != nil {
+			slog.Error(fmt.Sprintf("[%s] Logout failed: %v", statusKey, err))
+			return false
+		}
+		online, _, err := portal.DrcomLegacyStatus(instance.bind, baseURL, instance.UserAgent)
+		if err != nil {
+			slog.Error(fmt.Sprintf("[%s] Logout failed: %v", statusKey, err))
+			return false
+		} else if online {
+			slog.Error(fmt.Sprintf("[%s] Logout failed: still online after logout.", statusKey))
+			return false
+		}
+	} else {
+		logoutStat, err := portal.DrcomEportalLogout(instance.bind, baseURL, drcomPort(instance), instance.UserAgent, drcomClient(instance))
+		if err != nil {
+			slog.Error(fmt.Sprintf("[%s] Logout failed: %v", statusKey, err))
+			return false
+		} else if !logoutStat.OK() {
+			slog.Error(fmt.Sprintf("[%s] Logout failed: %s", statusKey, logoutStat.Msg))
+			return false
+		}
+	}
+
+	slog.Info(fmt.Sprintf("[%s] Logged out successfully.", statusKey))
+	return true
+}
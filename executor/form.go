@@ -0,0 +1,104 @@
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/summonhim/gzgspd/config"
+	"github.com/summonhim/gzgspd/portal"
+)
+
+// formProvider 按规则填写网页表单的通用登录
+type formProvider struct{}
+
+// 将配置转换为 portal 的规则
+func formRule(cfg *config.ConfigForm) *portal.FormRule {
+	fields := func(in []config.ConfigFormField) []portal.FormField {
+		var out []portal.FormField
+		for _, f := range in {
+			out = append(out, portal.FormField{Name: f.Name, Value: f.Value})
+		}
+		return out
+	}
+	return &portal.FormRule{
+		URL:          cfg.URL,
+		PortalMatch:  cfg.PortalMatch,
+		Form:         cfg.Form,
+		Action:       cfg.Action,
+		Method:       cfg.Method,
+		Fields:       fields(cfg.Fields),
+		Cookies:      fields(cfg.Cookies),
+		Success:      cfg.Success,
+		SuccessJSON:  cfg.SuccessJSON,
+		SuccessValue: cfg.SuccessValue,
+		LogoutURL:    cfg.LogoutURL,
+	}
+}
+
+// 模板中可使用的值
+func formValues(instance *WorkerInstance, link string) portal.FormValues {
+	values := portal.FormValues{
+		Username: instance.Username,
+		Password: instance.Password,
+		IP:       instance.LoginIfIP,
+		MAC:      instance.MAC,
+		URL:      link,
+	}
+	if u, err := url.Parse(link); err == nil {
+		values.Query = u.Query()
+	}
+	return values
+}
+
+func (formProvider) login(instance *WorkerInstance, statusKey string) bool {
+	rule := formRule(instance.Form)
+
+	// 检查是否需要登录
+	slog.Debug(fmt.Sprintf("[%s] Checking portal if login is required.", statusKey))
+	walk := portal.FormPortalChecker(instance.bind, instance.cookieJar(), instance.KAliveLink, rule.PortalMatch)
+	logPortalWalk(statusKey, walk)
+	needLogin, needLoginUrl := walk.Found(), walk.URL
+	if !needLogin {
+		return true
+	}
+
+	setWorkerStatus(statusKey, StateLoggingIn)
+	slog.Info(fmt.Sprintf("[%s] Login require.", statusKey))
+
+	result, err := portal.FormLogin(instance.bind, instance.cookieJar(), instance.UserAgent, rule, formValues(instance, needLoginUrl))
+	if err != nil {
+		setWorkerStatus(statusKey, StateNotLoggedIn)
+		slog.Error(fmt.Sprintf("[%s] Login failed: %v", statusKey, err))
+		return false
+	}
+	slog.Debug(fmt.Sprintf("[%s] Form submitted, response %d from %s.", statusKey, result.Status, result.URL))
+
+	// 规则没有成功条件时以是否仍被重定向为准
+	success := result.Success
+	if !result.Checked {
+		success = !portal.FormPortalChecker(instance.bind, instance.cookieJar(), instance.KAliveLink, rule.PortalMatch).Found()
+	}
+	if !success {
+		setWorkerStatus(statusKey, StateNotLoggedIn)
+		slog.Error(fmt.Sprintf("[%s] Login failed: response from %s does not match the success rule.", statusKey, result.URL))
+		return false
+	}
+
+	instance.Session = map[string]string{"url": needLoginUrl}
+	instance.saveSession(statusKey)
+	setWorkerStatus(statusKey, StateLoggedIn)
+	slog.Info(fmt.Sprintf("[%s] Login successfully!", statusKey))
+	return true
+}
+
+func (formProvider) logout(instance *WorkerInstance, statusKey string) bool {
+	err := portal.FormLogout(instance.bind, instance.cookieJar(), instance.UserAgent, formRule(instance.Form), formValues(instance, instance.Session["url"]))
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] Logout failed: %v", statusKey, err))
+		return false
+	}
+
+	slog.Info(fmt.Sprintf("[%s] Logged out successfully.", statusKey))
+	return true
+}
@@ -0,0 +1,105 @@
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/summonhim/gzgspd/config"
+	"github.com/summonhim/gzgspd/nnet"
+)
+
+// 实例 macvlan 子接口的选项
+func macvlanOptions(cfg config.ConfigInstance) nnet.MacvlanOptions {
+	// 未指定名称与 MAC 时根据实例标识生成，重启后保持不变，同一用户的不同实例互不冲突
+	// 实例配置了 mac 时，子接口使用该 MAC 地址
+	opts := nnet.MacvlanOptions{
+		Parent: cfg.Macvlan.Parent,
+		Name:   cfg.MacvlanName(),
+		MAC:    cfg.Macvlan.MAC,
+		Mode:   cfg.Macvlan.Mode,
+	}
+	if opts.MAC == "" {
+		opts.MAC = cfg.MACAddress
+	}
+	if opts.MAC == "" {
+		opts.MAC = nnet.GenerateMAC(cfg.Key())
+	}
+	return opts
+}
+
+// 创建实例的 macvlan 子接口，返回子接口名称
+func setupMacvlan(cfg config.ConfigInstance, statusKey string) (string, error) {
+	opts := macvlanOptions(cfg)
+	if err := nnet.CreateMacvlan(opts, cfg.Netns); err != nil {
+		return "", err
+	}
+	slog.Info(fmt.Sprintf("[%s] Created macvlan %s on %s with mac %s.", statusKey, opts.Name, opts.Parent, opts.MAC))
+	return opts.Name, nil
+}
+
+// 删除实例的 macvlan 子接口
+func teardownMacvlan(ifname string, netns string, statusKey string) {
+	if err := nnet.DeleteMacvlan(ifname, netns); err != nil {
+		slog.Error(fmt.Sprintf("[%s] Failed to remove macvlan %s: %v", statusKey, ifname, err))
+		return
+	}
+	slog.Info(fmt.Sprintf("[%s] Removed macvlan %s.", statusKey, ifname))
+}
+
+// 为 daemon 创建的接口获取 IPv4 地址，返回释放地址的清理函数
+// 使用内置 DHCP 客户端时同时返回该客户端，供工作循环接收租约变化
+// 需在接口所在的网络命名空间内调用
+func acquireAddress(ifname string, mode string, statusKey string) (func(), *nnet.DHCPClient, error) {
+	release := func() {}
+
+	switch mode {
+	case "", "builtin":
+		slog.Debug(fmt.Sprintf("[%s] Requesting address for %s with built-in dhcp client.", statusKey, ifname))
+		client, err := nnet.StartDHCP(ifname)
+		if err != nil {
+			return release, nil, err
+		}
+		lease := client.Lease()
+		slog.Info(fmt.Sprintf("[%s] Got DHCP lease %s/%d via %s for %s.", statusKey, lease.IP, maskBits(lease.Mask), lease.Router, lease.LeaseTime))
+		return client.Stop, client, nil
+	case "udhcpc":
+		// 获取租约后退出，不负责续约
+		slog.Debug(fmt.Sprintf("[%s] Requesting address for %s with udhcpc.", statusKey, ifname))
+		out, err := exec.Command("udhcpc", "-i", ifname, "-n", "-q", "-t", "5").CombinedOutput()
+		if err != nil {
+			return release, nil, fmt.Errorf("udhcpc failed: %v: %s", err, out)
+		}
+	case "dhclient":
+		// dhclient 获取租约后转入后台续约，退出时释放
+		slog.Debug(fmt.Sprintf("[%s] Requesting address for %s with dhclient.", statusKey, ifname))
+		pidFile := filepath.Join("/run", "gzgspd-dhclient-"+ifname+".pid")
+		out, err := exec.Command("dhclient", "-1", "-pf", pidFile, ifname).CombinedOutput()
+		if err != nil {
+			return release, nil, fmt.Errorf("dhclient failed: %v: %s", err, out)
+		}
+		release = func() {
+			exec.Command("dhclient", "-r", "-pf", pidFile, ifname).Run()
+		}
+	case "none":
+		// 由系统网络配置（如 netifd、systemd-networkd）分配地址
+		slog.Debug(fmt.Sprintf("[%s] Waiting for address on %s.", statusKey, ifname))
+	}
+
+	for i := 0; i < 30; i++ {
+		if _, err := nnet.GetIfIP(ifname); err == nil {
+			return release, nil, nil
+		}
+		time.Sleep(time.Second)
+	}
+	return release, nil, fmt.Errorf("no IPv4 address assigned to %s", ifname)
+}
+
+// 子网掩码位数
+func maskBits(mask net.IPMask) int {
+	ones, _ := mask.Size()
+	return ones
+}
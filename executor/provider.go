@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// portalProvider 门户协议的登录实现
+type portalProvider interface {
+	// login 检查是否需要登录，需要时登录，返回是否成功
+	login(instance *WorkerInstance, statusKey string) bool
+	// logout 登出，返回是否成功
+	logout(instance *WorkerInstance, statusKey string) bool
+}
+
+type telecomProvider struct{}
+
+func (telecomProvider) login(instance *WorkerInstance, statusKey string) bool {
+	return telecomLogin(instance, statusKey)
+}
+
+func (telecomProvider) logout(instance *WorkerInstance, statusKey string) bool {
+	return telecomLogout(instance, statusKey)
+}
+
+// 实例配置的门户协议
+func providerOf(instance *WorkerInstance) portalProvider {
+	switch instance.Provider {
+	case "ruijie":
+		return ruijieProvider{}
+	case "srun":
+		return srunProvider{}
+	case "drcom":
+		return drcomProvider{}
+	case "form":
+		return formProvider{}
+	default:
+		return telecomProvider{}
+	}
+}
+
+func doLogin(instance *WorkerInstance, statusKey string) bool {
+	return providerOf(instance).login(instance, statusKey)
+}
+
+func doLogout(instance *WorkerInstance, statusKey string) bool {
+	setWorkerStatus(statusKey, StateLoggingOut)
+	slog.Info(fmt.Sprintf("[%s] Logging out...", statusKey))
+	if !providerOf(instance).logout(instance, statusKey) {
+		return false
+	}
+	// 登出后保存的会话已失效
+	instance.clearSession(statusKey)
+	return true
+}
+
+// 设置实例状态
+func setWorkerStatus(statusKey string, state WorkerState) {
+	WorkerStatusLock.Lock()
+	WorkerStatus[statusKey] = state
+	WorkerStatusLock.Unlock()
+}
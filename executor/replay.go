@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/summonhim/gzgspd/config"
+	"github.com/summonhim/gzgspd/nnet"
+)
+
+// 录制时保存实例的地址与密码，密码在记录中会被隐藏
+func recordInstance(instance *WorkerInstance, statusKey string) {
+	nnet.AddSecret(instance.Password)
+	nnet.RecordInstance(statusKey, nnet.RecordedInstance{
+		Interface: instance.LoginIf,
+		IP:        instance.LoginIfIP,
+		MAC:       instance.LoginIfMAC,
+	})
+}
+
+// Replay 使用录制的地址离线执行一次登录与登出，请求均由记录回应
+func Replay(cfg config.ConfigInstance, statusKey string) error {
+	rec, ok := nnet.ReplayInstance(statusKey)
+	if !ok {
+		return fmt.Errorf("instance is not in the capture")
+	}
+
+	instance := &WorkerInstance{
+		ConfigInstance: cfg,
+		LoginIf:        rec.Interface,
+		LoginIfIP:      rec.IP,
+		LoginIfMAC:     rec.MAC,
+		MAC:            rec.MAC,
+	}
+	slog.Info(fmt.Sprintf("[%s] Replaying with interface %s (%s|%s).", statusKey, instance.LoginIf, instance.LoginIfIP, instance.MAC))
+	applyMACOverride(instance, statusKey)
+	// 回放时请求由记录回应，绑定只用于区分实例的地址
+	instance.bind = nnet.NewBinding(instance.LoginIfIP, nnet.BindOptions{})
+
+	if !doLogin(instance, statusKey) {
+		return fmt.Errorf("login failed")
+	}
+	if !doLogout(instance, statusKey) {
+		return fmt.Errorf("logout failed")
+	}
+	return nil
+}
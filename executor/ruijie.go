@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/summonhim/gzgspd/portal"
+)
+
+// ruijieProvider 锐捷 ePortal
+type ruijieProvider struct{}
+
+// 锐捷门户地址，优先使用登录时保存的地址
+func ruijieBaseURL(instance *WorkerInstance) string {
+	if u := instance.Session["url"]; u != "" {
+		return u
+	}
+	if instance.Ruijie != nil {
+		return instance.Ruijie.URL
+	}
+	return ""
+}
+
+func (ruijieProvider) login(instance *WorkerInstance, statusKey string) bool {
+	// 已有 userIndex 时先查询是否在线
+	baseURL := ruijieBaseURL(instance)
+	if userIndex := instance.Session["user_index"]; userIndex != "" && baseURL != "" {
+		info, err := portal.RuijieOnlineUserInfo(instance.bind, baseURL, instance.UserAgent, userIndex)
+		if err != nil {
+			slog.Debug(fmt.Sprintf("[%s] Failed to query online status: %v", statusKey, err))
+		} else if info.Result == "success" {
+			slog.Debug(fmt.Sprintf("[%s] Online as %s.", statusKey, info.UserName))
+			return true
+		}
+	}
+
+	// 检查是否需要登录
+	slog.Debug(fmt.Sprintf("[%s] Checking portal if login is required.", statusKey))
+	walk := portal.RuijiePortalChecker(instance.bind, instance.cookieJar(), instance.KAliveLink)
+	logPortalWalk(statusKey, walk)
+	needLogin, needLoginUrl := walk.Found(), walk.URL
+	if !needLogin {
+		return true
+	}
+
+	setWorkerStatus(statusKey, StateLoggingIn)
+	slog.Info(fmt.Sprintf("[%s] Login require.", statusKey))
+
+	nlu, err := url.Parse(needLoginUrl)
+	if err != nil {
+		setWorkerStatus(statusKey, StateNotLoggedIn)
+		slog.Error(fmt.Sprintf("[%s] Failed to parse redirect login link: %v", statusKey, err))
+		return false
+	}
+	baseURL = nlu.Scheme + "://" + nlu.Host
+	queryString := nlu.RawQuery
+	portalMAC := nlu.Query().Get("mac")
+	checkPortalMAC(instance, statusKey, "redirect", portalMAC)
+
+	service := ""
+	if instance.Ruijie != nil {
+		service = instance.Ruijie.Service
+	}
+
+	// 页面要求时使用 RSA 加密密码
+	password := instance.Password
+	encrypt := false
+	pageInfo, err := portal.RuijiePageInfo(instance.bind, baseURL, instance.UserAgent, queryString)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("[%s] Failed to fetch page info, login without password encryption: %v", statusKey, err))
+	} else if pageInfo.PasswordEncrypt == "true" {
+		password, err = portal.RuijieEncryptPassword(instance.Password, portalMAC, pageInfo.PublicKeyExponent, pageInfo.PublicKeyModulus)
+		if err != nil {
+			setWorkerStatus(statusKey, StateNotLoggedIn)
+			slog.Error(fmt.Sprintf("[%s] Failed to encrypt password: %v", statusKey, err))
+			return false
+		}
+		encrypt = true
+	}
+
+	// 登录
+	loginStat, err := portal.RuijieLogin(instance.bind, baseURL, instance.UserAgent, instance.Username, password, service, queryString, encrypt)
+	if err != nil {
+		setWorkerStatus(statusKey, StateNotLoggedIn)
+		slog.Error(fmt.Sprintf("[%s] Login failed: %v", statusKey, err))
+		return false
+	}
+	if loginStat.Result != "success" {
+		setWorkerStatus(statusKey, StateNotLoggedIn)
+		slog.Error(fmt.Sprintf("[%s] Login failed: %s", statusKey, loginStat.Message))
+		return false
+	}
+
+	instance.Session = map[string]string{
+		"url":        baseURL,
+		"user_index": loginStat.UserIndex,
+	}
+	instance.saveSession(statusKey)
+	setWorkerStatus(statusKey, StateLoggedIn)
+	slog.Info(fmt.Sprintf("[%s] Login successfully!", statusKey))
+	return true
+}
+
+func (ruijieProvider) logout(instance *WorkerInstance, statusKey string) bool {
+	baseURL := ruijieBaseURL(instance)
+	userIndex := instance.Session["user_index"]
+	if baseURL == "" || userIndex == "" {
+		slog.Error(fmt.Sprintf("[%s] Logout failed: no saved session to log out.", statusKey))
+		return false
+	}
+
+	logoutStat, err := portal.RuijieLogout(instance.bind, baseURL, instance.UserAgent, userIndex)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] Logout failed: %v", statusKey, err))
+		return false
+	} else if logoutStat.Result != "success" {
+		slog.Error(fmt.Sprintf("[%s] Logout failed: %s", statusKey, logoutStat.Message))
+		return false
+	}
+
+	slog.Info(fmt.Sprintf("[%s] Logged out successfully.", statusKey))
+	return true
+}
@@ -0,0 +1,139 @@
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/summonhim/gzgspd/portal"
+)
+
+// srunProvider 深澜 portal
+type srunProvider struct{}
+
+// 深澜门户地址，优先使用登录时保存的地址
+func srunBaseURL(instance *WorkerInstance) string {
+	if u := instance.Session["url"]; u != "" {
+		return u
+	}
+	if instance.Srun != nil {
+		return instance.Srun.URL
+	}
+	return ""
+}
+
+// 深澜 ac_id，依次使用保存的、配置的与默认的值
+func srunAcID(instance *WorkerInstance) string {
+	if acID := instance.Session["ac_id"]; acID != "" {
+		return acID
+	}
+	if instance.Srun != nil && instance.Srun.AcID != "" {
+		return instance.Srun.AcID
+	}
+	return "1"
+}
+
+func (srunProvider) login(instance *WorkerInstance, statusKey string) bool {
+	baseURL := srunBaseURL(instance)
+	acID := srunAcID(instance)
+
+	if baseURL != "" {
+		// 已知门户地址时直接查询在线状态
+		slog.Debug(fmt.Sprintf("[%s] Checking online status.", statusKey))
+		info, err := portal.SrunUserInfo(instance.bind, baseURL, instance.UserAgent)
+		if err != nil {
+			slog.Debug(fmt.Sprintf("[%s] Failed to query online status: %v", statusKey, err))
+		} else if info.Error == "ok" {
+			slog.Debug(fmt.Sprintf("[%s] Online as %s (%s).", statusKey, info.UserName, info.OnlineIP))
+			return true
+		}
+	} else {
+		// 否则从跳转链接中得到门户地址
+		slog.Debug(fmt.Sprintf("[%s] Checking portal if login is required.", statusKey))
+		walk := portal.SrunPortalChecker(instance.bind, instance.cookieJar(), instance.KAliveLink)
+		logPortalWalk(statusKey, walk)
+		needLogin, needLoginUrl := walk.Found(), walk.URL
+		if !needLogin {
+			return true
+		}
+
+		nlu, err := url.Parse(needLoginUrl)
+		if err != nil {
+			setWorkerStatus(statusKey, StateNotLoggedIn)
+			slog.Error(fmt.Sprintf("[%s] Failed to parse redirect login link: %v", statusKey, err))
+			return false
+		}
+		baseURL = nlu.Scheme + "://" + nlu.Host
+		if instance.Srun == nil || instance.Srun.AcID == "" {
+			if v := portal.SrunAcID(nlu); v != "" {
+				acID = v
+			}
+		}
+	}
+
+	setWorkerStatus(statusKey, StateLoggingIn)
+	slog.Info(fmt.Sprintf("[%s] Login require.", statusKey))
+
+	// 获取 challenge
+	challenge, err := portal.SrunChallenge(instance.bind, baseURL, instance.UserAgent, instance.Username, instance.LoginIfIP)
+	if err != nil {
+		setWorkerStatus(statusKey, StateNotLoggedIn)
+		slog.Error(fmt.Sprintf("[%s] Failed to get challenge: %v", statusKey, err))
+		return false
+	} else if challenge.Error != "ok" || challenge.Challenge == "" {
+		setWorkerStatus(statusKey, StateNotLoggedIn)
+		slog.Error(fmt.Sprintf("[%s] Failed to get challenge: %s", statusKey, challenge.Message()))
+		return false
+	}
+	ip := challenge.ClientIP
+	if ip == "" {
+		ip = instance.LoginIfIP
+	}
+
+	// 登录
+	loginStat, err := portal.SrunLogin(instance.bind, baseURL, instance.UserAgent, instance.Username, instance.Password, acID, ip, challenge.Challenge)
+	if err != nil {
+		setWorkerStatus(statusKey, StateNotLoggedIn)
+		slog.Error(fmt.Sprintf("[%s] Login failed: %v", statusKey, err))
+		return false
+	}
+	if loginStat.Error != "ok" {
+		setWorkerStatus(statusKey, StateNotLoggedIn)
+		slog.Error(fmt.Sprintf("[%s] Login failed: %s", statusKey, loginStat.Message()))
+		return false
+	}
+
+	instance.Session = map[string]string{
+		"url":   baseURL,
+		"ac_id": acID,
+		"ip":    ip,
+	}
+	instance.saveSession(statusKey)
+	setWorkerStatus(statusKey, StateLoggedIn)
+	slog.Info(fmt.Sprintf("[%s] Login successfully!", statusKey))
+	return true
+}
+
+func (srunProvider) logout(instance *WorkerInstance, statusKey string) bool {
+	baseURL := srunBaseURL(instance)
+	if baseURL == "" {
+		slog.Error(fmt.Sprintf("[%s] Logout failed: no saved session or srun url to log out.", statusKey))
+		return false
+	}
+	ip := instance.Session["ip"]
+	if ip == "" {
+		ip = instance.LoginIfIP
+	}
+
+	logoutStat, err := portal.SrunLogout(instance.bind, baseURL, instance.UserAgent, instance.Username, srunAcID(instance), ip)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] Logout failed: %v", statusKey, err))
+		return false
+	} else if logoutStat.Error != "ok" {
+		slog.Error(fmt.Sprintf("[%s] Logout failed: %s", statusKey, logoutStat.Message()))
+		return false
+	}
+
+	slog.Info(fmt.Sprintf("[%s] Logged out successfully.", statusKey))
+	return true
+}
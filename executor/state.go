@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// 会话状态保存目录，为空时不保存；重新加载配置时会被修改，读写需加锁
+var (
+	stateDir     string
+	stateDirLock sync.Mutex
+)
+
+// SetStateDir 设置会话状态保存目录，运行中的实例之后的保存与删除使用新目录
+func SetStateDir(dir string) {
+	stateDirLock.Lock()
+	defer stateDirLock.Unlock()
+	stateDir = dir
+}
+
+func getStateDir() string {
+	stateDirLock.Lock()
+	defer stateDirLock.Unlock()
+	return stateDir
+}
+
+// SessionState 登录成功后保存的会话信息，重启后用于登出
+type SessionState struct {
+	LoginScheme string            `json:"login_scheme"`
+	LoginHost   string            `json:"login_host"`
+	Wlanuserip  string            `json:"wlanuserip"`
+	Wlanacname  string            `json:"wlanacname"`
+	WlanacIp    string            `json:"wlanac_ip"`
+	MAC         string            `json:"mac"`
+	Version     int               `json:"version"`
+	GroupID     int               `json:"group_id"`
+	LogoutUID   string            `json:"logout_uid"`
+	Provider    string            `json:"provider,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+	SavedAt     time.Time         `json:"saved_at"`
+}
+
+var stateFileNameRe = regexp.MustCompile(`[^A-Za-z0-9@._-]`)
+
+// 会话状态文件路径
+func stateFile(dir string, statusKey string) string {
+	return filepath.Join(dir, stateFileNameRe.ReplaceAllString(statusKey, "_")+".json")
+}
+
+// 读取会话状态，未保存时返回 nil
+func loadSession(statusKey string) (*SessionState, error) {
+	dir := getStateDir()
+	if dir == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(stateFile(dir, statusKey))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var state SessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %v", stateFile(dir, statusKey), err)
+	}
+	return &state, nil
+}
+
+// 保存会话状态，先写入临时文件再替换，避免崩溃时留下半个文件
+func saveSession(statusKey string, state *SessionState) error {
+	dir := getStateDir()
+	if dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := stateFile(dir, statusKey)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// 删除会话状态文件
+func clearSession(statusKey string) error {
+	dir := getStateDir()
+	if dir == "" {
+		return nil
+	}
+
+	err := os.Remove(stateFile(dir, statusKey))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// 将登录成功后的会话信息写入状态文件
+func (w *WorkerInstance) saveSession(statusKey string) {
+	err := saveSession(statusKey, &SessionState{
+		LoginScheme: w.LoginScheme,
+		LoginHost:   w.LoginHost,
+		Wlanuserip:  w.Wlanuserip,
+		Wlanacname:  w.Wlanacname,
+		WlanacIp:    w.WlanacIp,
+		MAC:         w.MAC,
+		Version:     w.Version,
+		GroupID:     w.GroupID,
+		LogoutUID:   w.LogoutUID,
+		Provider:    w.Provider,
+		Extra:       w.Session,
+		SavedAt:     time.Now(),
+	})
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] Failed to save session state: %v", statusKey, err))
+	}
+}
+
+// 登出成功后删除状态文件，避免之后使用失效的会话信息
+func (w *WorkerInstance) clearSession(statusKey string) {
+	if err := clearSession(statusKey); err != nil {
+		slog.Error(fmt.Sprintf("[%s] Failed to clear session state: %v", statusKey, err))
+	}
+}
+
+// 从状态文件恢复上次登录的会话信息，供登出时优先使用
+func (w *WorkerInstance) restoreSession(statusKey string) {
+	state, err := loadSession(statusKey)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("[%s] Failed to load session state: %v", statusKey, err))
+		return
+	}
+	if state == nil {
+		return
+	}
+
+	w.LoginScheme = state.LoginScheme
+	w.LoginHost = state.LoginHost
+	w.Wlanuserip = state.Wlanuserip
+	w.Wlanacname = state.Wlanacname
+	w.WlanacIp = state.WlanacIp
+	if w.MACAddress == "" && state.MAC != "" {
+		w.MAC = state.MAC
+	}
+	w.Version = state.Version
+	w.GroupID = state.GroupID
+	w.LogoutUID = state.LogoutUID
+	if state.Provider == w.Provider {
+		w.Session = state.Extra
+	}
+	slog.Debug(fmt.Sprintf("[%s] Restored session state saved at %s.", statusKey, state.SavedAt.Format(time.RFC3339)))
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/summonhim/gzgspd/config"
+)
+
+// Supervisor 按实例标识管理运行中的 Worker，重新加载配置时只重启有变化的实例
+type Supervisor struct {
+	lock    sync.Mutex
+	workers map[string]*supervisedWorker
+}
+
+type supervisedWorker struct {
+	cfg  config.ConfigInstance
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewSupervisor 创建 Supervisor
+func NewSupervisor() *Supervisor {
+	WorkerStatusLock.Lock()
+	if WorkerStatus == nil {
+		WorkerStatus = make(map[string]WorkerState)
+	}
+	WorkerStatusLock.Unlock()
+	return &Supervisor{workers: make(map[string]*supervisedWorker)}
+}
+
+// Apply 使运行中的实例与配置一致：停止已删除的实例，重启配置有变化的实例，启动新增的实例
+func (s *Supervisor) Apply(instances []config.ConfigInstance) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	wanted := make(map[string]config.ConfigInstance, len(instances))
+	for _, inst := range instances {
+		wanted[inst.Key()] = inst
+	}
+
+	// 先停止，登出后再以新配置启动，避免同一账号并发登录
+	var stop []string
+	for key, w := range s.workers {
+		cfg, ok := wanted[key]
+		switch {
+		case !ok:
+			slog.Info(fmt.Sprintf("[%s] Instance removed from configuration, stopping.", key))
+		case !reflect.DeepEqual(cfg, w.cfg):
+			slog.Info(fmt.Sprintf("[%s] Configuration changed, restarting instance.", key))
+		default:
+			continue
+		}
+		stop = append(stop, key)
+	}
+	s.stop(stop)
+	for _, key := range stop {
+		if _, ok := wanted[key]; !ok {
+			WorkerStatusLock.Lock()
+			delete(WorkerStatus, key)
+			WorkerStatusLock.Unlock()
+		}
+	}
+
+	for _, inst := range instances {
+		key := inst.Key()
+		if _, ok := s.workers[key]; ok {
+			continue
+		}
+		s.start(key, inst)
+	}
+}
+
+func (s *Supervisor) start(key string, cfg config.ConfigInstance) {
+	w := &supervisedWorker{
+		cfg:  cfg,
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	s.workers[key] = w
+
+	WorkerStatusLock.Lock()
+	WorkerStatus[key] = StateStarting
+	WorkerStatusLock.Unlock()
+
+	go func() {
+		defer close(w.done)
+		Worker(cfg, key, w.quit)
+	}()
+}
+
+// stop 同时停止多个实例并等待其登出
+func (s *Supervisor) stop(keys []string) {
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		w := s.workers[key]
+		delete(s.workers, key)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			close(w.quit)
+			<-w.done
+		}()
+	}
+	wg.Wait()
+}
+
+// StopAll 停止并登出所有实例
+func (s *Supervisor) StopAll() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	keys := make([]string, 0, len(s.workers))
+	for key := range s.workers {
+		keys = append(keys, key)
+	}
+	s.stop(keys)
+}
@@ -1,9 +1,13 @@
 package executor
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"sync"
 	"time"
@@ -17,6 +21,7 @@ type WorkerInstance struct {
 	config.ConfigInstance
 	LoginIf      string
 	LoginIfIP    string
+	LoginIfMAC   string
 	LoginScheme  string
 	LoginHost    string
 	Wlanuserip   string
@@ -32,6 +37,16 @@ type WorkerInstance struct {
 	UUID         string
 	GroupID      int
 	LogoutUID    string
+	LeaseDNS     []string
+
+	// 非电信门户保存的会话信息，如锐捷的 userIndex
+	Session map[string]string
+	// 门户请求共用的 Cookie
+	CookieJar http.CookieJar
+	// 从登录IP发送门户请求的绑定，地址或选项变化后重新创建
+	bind *nnet.Binding
+	// 已提示未校验证书，每个实例只提示一次
+	tlsWarned bool
 }
 
 type WorkerState string
@@ -56,14 +71,34 @@ func (w *WorkerInstance) GetStringFallback(val string, defaultVal string) string
 	return defaultVal
 }
 
-func doLogin(instance *WorkerInstance, statusKey string) bool {
+// cookieJar 实例的 Cookie，同一实例的检测、登录与登出共用
+func (w *WorkerInstance) cookieJar() http.CookieJar {
+	if w.CookieJar == nil {
+		w.CookieJar, _ = cookiejar.New(nil)
+	}
+	return w.CookieJar
+}
+
+// logPortalWalk 输出门户检测经过的跳转
+func logPortalWalk(statusKey string, walk *portal.PortalWalk) {
+	for i, hop := range walk.Chain {
+		slog.Debug(fmt.Sprintf("[%s] Hop %d (%s, %d): %s", statusKey, i, hop.Via, hop.Status, hop.URL))
+	}
+	slog.Debug(fmt.Sprintf("[%s] Need login: %t Redirect link: %s", statusKey, walk.Found(), walk.URL))
+}
+
+// telecomLogin 电信 ePortal 登录
+func telecomLogin(instance *WorkerInstance, statusKey string) bool {
 	// 检查是否需要登录
 	slog.Debug(fmt.Sprintf("[%s] Checking portal if login is required.", statusKey))
-	needLogin, needLoginUrl := portal.TelecomPortalChecker(
-		instance.LoginIfIP,
+	walk := portal.TelecomPortalChecker(
+		instance.bind,
+		instance.cookieJar(),
 		instance.KAliveLink,
+		instance.SiteProfile.PortalKeywords,
 	)
-	slog.Debug(fmt.Sprintf("[%s] Need login: %t Redirect link: %s", statusKey, needLogin, needLoginUrl))
+	logPortalWalk(statusKey, walk)
+	needLogin, needLoginUrl := walk.Found(), walk.URL
 
 	if needLogin && needLoginUrl != "" {
 		WorkerStatusLock.Lock()
@@ -93,33 +128,43 @@ func doLogin(instance *WorkerInstance, statusKey string) bool {
 		instance.LoginHost = nlu.Host
 		instance.Wlanuserip = tWlanuserip
 		instance.Wlanacname = tWlanacname
-		instance.MAC = tMAC
+		if instance.MACAddress != "" {
+			checkPortalMAC(instance, statusKey, "redirect", tMAC)
+		} else {
+			instance.MAC = tMAC
+		}
 		instance.Vlan = tVlan
 		instance.HostName = tHostName
 		instance.Rand = tRand
 
+		client, err := portal.NewTelecomClient(instance.bind, instance.LoginScheme, instance.LoginHost, instance.UserAgent)
+		if err != nil {
+			setWorkerStatus(statusKey, StateNotLoggedIn)
+			slog.Error(fmt.Sprintf("[%s] Login failed: %v", statusKey, err))
+			return false
+		}
+
 		// 获取登录基本信息
-		portalConfig, err := portal.TelecomPortalJsonAction(
-			instance.LoginIfIP,
-			instance.LoginScheme,
-			instance.LoginHost,
-			instance.UserAgent,
-			instance.Wlanuserip,
-			instance.Wlanacname,
-			instance.MAC,
-			instance.Vlan,
-			instance.HostName,
-			instance.Rand,
-		)
+		portalConfig, err := client.PortalInfo(portal.PortalInfoRequest{
+			WlanUserIP: instance.Wlanuserip,
+			WlanAcName: instance.Wlanacname,
+			MAC:        instance.MAC,
+			Vlan:       instance.Vlan,
+			HostName:   instance.HostName,
+			Rand:       instance.Rand,
+		})
 		if err != nil {
 			WorkerStatusLock.Lock()
 			WorkerStatus[statusKey] = StateNotLoggedIn
 			WorkerStatusLock.Unlock()
 			slog.Error(fmt.Sprintf("[%s] Failed to fetch Portal Json Action: %v", statusKey, err))
+			logTelecomError(statusKey, err)
 			return false
 		}
 		// slog.Debug(fmt.Sprintf("[%s] Portal Config: %v", instance.Username, portalConfig))
 
+		checkPortalMAC(instance, statusKey, "PortalJsonAction", portalConfig.PortalForm.Mac)
+
 		// 提取登录基本信息
 		instance.WlanacIp = portalConfig.ServerForm.Serverip
 		instance.Version = portalConfig.ServerForm.PortalVer
@@ -128,33 +173,30 @@ func doLogin(instance *WorkerInstance, statusKey string) bool {
 		instance.UUID = portalConfig.PortalConfig.UUID
 
 		// 登录
-		loginStat, err := portal.TelecomQuickAuth(
-			instance.LoginIfIP,
-			instance.LoginScheme,
-			instance.LoginHost,
-			instance.UserAgent,
-			instance.Username,
-			instance.Password,
-			instance.Wlanuserip,
-			instance.Wlanacname,
-			instance.WlanacIp,
-			instance.Vlan,
-			instance.MAC,
-			instance.Version,
-			instance.PortalPageID,
-			instance.TimeStamp,
-			instance.UUID,
-			"0",
-			instance.HostName,
-			instance.Rand,
-		)
-		if loginStat.Code != "0" || err != nil {
+		loginStat, err := client.Login(portal.LoginRequest{
+			UserID:       instance.Username,
+			Password:     instance.Password,
+			WlanUserIP:   instance.Wlanuserip,
+			WlanAcName:   instance.Wlanacname,
+			WlanAcIP:     instance.WlanacIp,
+			Vlan:         instance.Vlan,
+			MAC:          instance.MAC,
+			Version:      instance.Version,
+			PortalPageID: instance.PortalPageID,
+			Timestamp:    instance.TimeStamp,
+			UUID:         instance.UUID,
+			PortalType:   instance.SiteProfile.PortalType,
+			HostName:     instance.HostName,
+			Rand:         instance.Rand,
+		})
+		if err != nil || loginStat.Code != "0" {
 			WorkerStatusLock.Lock()
 			WorkerStatus[statusKey] = StateNotLoggedIn
 			WorkerStatusLock.Unlock()
 
 			if err != nil {
 				slog.Error(fmt.Sprintf("[%s] Login failed: %v", statusKey, err))
+				logTelecomError(statusKey, err)
 			} else if loginStat.Code != "0" {
 				slog.Error(fmt.Sprintf("[%s] Login failed: %s", statusKey, loginStat.Message))
 			} else {
@@ -164,6 +206,7 @@ func doLogin(instance *WorkerInstance, statusKey string) bool {
 		}
 		instance.GroupID = loginStat.GroupID
 		instance.LogoutUID = loginStat.UserID
+		instance.saveSession(statusKey)
 
 		WorkerStatusLock.Lock()
 		WorkerStatus[statusKey] = StateLoggedIn
@@ -175,45 +218,59 @@ func doLogin(instance *WorkerInstance, statusKey string) bool {
 	return true
 }
 
-func doLogout(instance *WorkerInstance, statusKey string) {
-	WorkerStatusLock.Lock()
-	WorkerStatus[statusKey] = StateLoggingOut
-	WorkerStatusLock.Unlock()
-
-	slog.Info(fmt.Sprintf("[%s] Logging out...", statusKey))
+// 门户返回异常响应时，在调试日志中输出完整的响应体
+func logTelecomError(statusKey string, err error) {
+	var terr *portal.TelecomError
+	if errors.As(err, &terr) {
+		slog.Debug(fmt.Sprintf("[%s] %s response body: %s", statusKey, terr.Action, terr.Body))
+	}
+}
 
+// telecomLogout 电信 ePortal 登出
+func telecomLogout(instance *WorkerInstance, statusKey string) bool {
 	tMac, _ := nnet.GetIPMAC(instance.LoginIfIP)
 
+	// 没有登录或保存的会话信息时使用站点配置
+	site := instance.SiteProfile
 	if instance.Version == 0 {
-		instance.Version = 4
+		instance.Version = site.Version
 	}
 	if instance.GroupID == 0 {
-		instance.GroupID = 19
+		instance.GroupID = site.GroupID
 	}
 
-	logoutStat, err := portal.TelecomQuickAuthDisconn(
-		instance.LoginIfIP,
-		instance.GetStringFallback(instance.LoginScheme, "https"),
-		instance.GetStringFallback(instance.LoginHost, "10.20.16.5"),
+	client, err := portal.NewTelecomClient(
+		instance.bind,
+		instance.GetStringFallback(instance.LoginScheme, site.Scheme),
+		instance.GetStringFallback(instance.LoginHost, site.Host),
 		instance.UserAgent,
-		instance.GetStringFallback(instance.WlanacIp, "10.20.16.2"),
-		instance.GetStringFallback(instance.Wlanuserip, instance.LoginIfIP),
-		instance.GetStringFallback(instance.Wlanacname, "NFV-BASE-01"),
-		instance.Version,
-		"0",
-		instance.GetStringFallback(instance.LogoutUID, instance.Username+"@SSGSXY"),
-		instance.GetStringFallback(instance.MAC, tMac),
-		instance.GroupID,
-		"0",
 	)
-
 	if err != nil {
 		slog.Error(fmt.Sprintf("[%s] Logout failed: %v", statusKey, err))
+		return false
+	}
+	logoutStat, err := client.Logout(portal.LogoutRequest{
+		WlanAcIP:      instance.GetStringFallback(instance.WlanacIp, site.WlanacIp),
+		WlanUserIP:    instance.GetStringFallback(instance.Wlanuserip, instance.LoginIfIP),
+		WlanAcName:    instance.GetStringFallback(instance.Wlanacname, site.Wlanacname),
+		Version:       instance.Version,
+		PortalType:    site.PortalType,
+		UserID:        instance.GetStringFallback(instance.LogoutUID, instance.Username+site.UserSuffix),
+		MAC:           instance.GetStringFallback(instance.MAC, tMac),
+		GroupID:       instance.GroupID,
+		ClearOperator: "0",
+	})
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] Logout failed: %v", statusKey, err))
+		logTelecomError(statusKey, err)
+		return false
 	} else if logoutStat.Code != "0" {
 		slog.Error(fmt.Sprintf("[%s] Logout failed: %s", statusKey, logoutStat.Message))
-	} else {
-		slog.Info(fmt.Sprintf("[%s] Logged out successfully.", statusKey))
+		return false
 	}
+
+	slog.Info(fmt.Sprintf("[%s] Logged out successfully.", statusKey))
+	return true
 }
 
 // 解析网络接口设置
@@ -246,9 +303,157 @@ func parseInterface(instanceIf string) (string, string, string, error) {
 	}
 }
 
-// 工作函数
-func Worker(cfg config.ConfigInstance, statusKey string, quitSender <-chan struct{}) {
-	slog.Info(fmt.Sprintf("[%s] Starting instance %s", statusKey, statusKey))
+// 使用配置的 MAC 地址代替接口 MAC 地址
+func applyMACOverride(instance *WorkerInstance, statusKey string) {
+	if instance.MACAddress == "" {
+		return
+	}
+	if instance.LoginIfMAC != "" && !nnet.EqualMAC(instance.LoginIfMAC, instance.MACAddress) {
+		slog.Warn(fmt.Sprintf("[%s] Interface %s has mac %s but %s is configured, the portal may see the interface mac.", statusKey, instance.LoginIf, instance.LoginIfMAC, instance.MACAddress))
+	}
+	instance.MAC = instance.MACAddress
+}
+
+// 检查门户返回的 MAC 地址是否与配置一致
+func checkPortalMAC(instance *WorkerInstance, statusKey string, source string, portalMAC string) {
+	if instance.MACAddress == "" || portalMAC == "" || nnet.EqualMAC(portalMAC, instance.MACAddress) {
+		return
+	}
+	slog.Warn(fmt.Sprintf("[%s] Portal %s reports mac %s but %s is configured.", statusKey, source, portalMAC, instance.MACAddress))
+}
+
+// 为当前登录IP创建实例自己的绑定，DNS查询从同一链路发出，并使用实例的 TLS 与代理设置
+func applyBindOptions(instance *WorkerInstance, statusKey string) {
+	servers := instance.DNS
+	if len(servers) == 0 {
+		servers = instance.LeaseDNS
+	}
+	if len(servers) == 0 {
+		servers = nnet.GetNetnsDNS(instance.Netns)
+	}
+	if len(servers) == 0 {
+		ifname := instance.LoginIf
+		if net.ParseIP(ifname) != nil {
+			ifname, _ = nnet.GetIPIfName(ifname)
+		}
+		tServers, err := nnet.GetIfDNS(ifname)
+		if err != nil {
+			slog.Debug(fmt.Sprintf("[%s] Link DNS servers not found, use system resolver: %v", statusKey, err))
+		}
+		servers = tServers
+	}
+	if len(servers) > 0 {
+		slog.Debug(fmt.Sprintf("[%s] Use DNS servers %v for %s.", statusKey, servers, instance.LoginIfIP))
+	}
+
+	// 旧地址或旧选项的连接不再使用
+	instance.releaseBinding()
+	instance.bind = nnet.NewBinding(instance.LoginIfIP, nnet.BindOptions{
+		DNS:   servers,
+		Netns: instance.Netns,
+		TLS:   instanceTLSConfig(instance, statusKey),
+		Proxy: instanceProxy(instance, statusKey),
+	})
+}
+
+// releaseBinding 关闭实例的绑定及其缓存的连接
+func (w *WorkerInstance) releaseBinding() {
+	if w.bind != nil {
+		w.bind.Close()
+		w.bind = nil
+	}
+}
+
+// 实例的代理，校验配置时已检查格式
+func instanceProxy(instance *WorkerInstance, statusKey string) *url.URL {
+	if instance.Proxy == "" {
+		return nil
+	}
+	proxy, err := url.Parse(instance.Proxy)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] Invalid proxy, connecting directly: %v", statusKey, err))
+		return nil
+	}
+	slog.Debug(fmt.Sprintf("[%s] Use proxy %s from %s.", statusKey, proxy.Redacted(), instance.LoginIfIP))
+	return proxy
+}
+
+// 实例的 TLS 配置，实例未设置时使用站点的设置
+func instanceTLSConfig(instance *WorkerInstance, statusKey string) *tls.Config {
+	opts := instance.TLS
+	if opts == nil {
+		opts = instance.SiteProfile.TLS
+	}
+	if opts == nil {
+		return nil
+	}
+
+	// 设置了 pin 时仍会校验服务器证书
+	if opts.InsecureSkipVerify && len(opts.PinSHA256) == 0 && !instance.tlsWarned {
+		instance.tlsWarned = true
+		slog.Warn(fmt.Sprintf("[%s] TLS certificate verification is DISABLED for portal connections (insecure_skip_verify). Anyone on the network can intercept the password.", statusKey))
+	}
+	config, err := nnet.NewTLSConfig(nnet.TLSOptions{
+		CAFile:             opts.CAFile,
+		PinSHA256:          opts.PinSHA256,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		MinVersion:         opts.MinVersion,
+	})
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] Invalid TLS options, using the system defaults: %v", statusKey, err))
+		return nil
+	}
+	return config
+}
+
+// 准备实例的网络环境后执行 fn，fn 返回后按相反顺序清理
+// 依次创建 macvlan 子接口、进入网络命名空间、修改接口 MAC 地址并获取地址
+// 配置了网络命名空间时，fn 在锁定的 OS 线程上于该命名空间内执行
+func runInstance(cfg config.ConfigInstance, statusKey string, fn func(cfg config.ConfigInstance, dhcp *nnet.DHCPClient)) error {
+	// 创建 macvlan 子接口，退出时删除
+	if cfg.Macvlan != nil {
+		ifname, err := setupMacvlan(cfg, statusKey)
+		if err != nil {
+			return fmt.Errorf("failed to create macvlan: %v", err)
+		}
+		defer teardownMacvlan(ifname, cfg.Netns, statusKey)
+		cfg.Interface = ifname
+	}
+
+	if cfg.Netns != "" {
+		slog.Info(fmt.Sprintf("[%s] Entering network namespace %s.", statusKey, cfg.Netns))
+	}
+	return nnet.RunInNetns(cfg.Netns, func() error {
+		// 登录前修改接口 MAC 地址，退出时恢复
+		if cfg.SetLinkMAC && cfg.Macvlan == nil {
+			oldMAC, err := nnet.SetIfMAC(cfg.Interface, cfg.MACAddress)
+			if err != nil {
+				return fmt.Errorf("failed to set interface mac: %v", err)
+			}
+			slog.Info(fmt.Sprintf("[%s] Set %s mac to %s.", statusKey, cfg.Interface, cfg.MACAddress))
+			defer func() {
+				if _, err := nnet.SetIfMAC(cfg.Interface, oldMAC); err != nil {
+					slog.Error(fmt.Sprintf("[%s] Failed to restore %s mac to %s: %v", statusKey, cfg.Interface, oldMAC, err))
+				}
+			}()
+		}
+
+		var dhcp *nnet.DHCPClient
+		if cfg.Macvlan != nil {
+			release, client, err := acquireAddress(cfg.Interface, cfg.Macvlan.DHCP, statusKey)
+			defer release()
+			if err != nil {
+				return fmt.Errorf("failed to acquire address: %v", err)
+			}
+			dhcp = client
+		}
+		fn(cfg, dhcp)
+		return nil
+	})
+}
+
+// 根据配置创建运行时实例，并恢复上次保存的会话信息
+func newWorkerInstance(cfg config.ConfigInstance, statusKey string, dhcp *nnet.DHCPClient) (*WorkerInstance, error) {
 	// 将配置写入当前内存中
 	instance := &WorkerInstance{
 		ConfigInstance: cfg,
@@ -257,29 +462,92 @@ func Worker(cfg config.ConfigInstance, statusKey string, quitSender <-chan struc
 	// 分析接口的IP
 	tLoginIf, tLoginIfIP, tMac, err := parseInterface(instance.Interface)
 	if err != nil {
-		slog.Error(fmt.Sprintf("[%s] Error parsing interface: %v", statusKey, err))
-		return
-	} else {
-		instance.LoginIf = tLoginIf
-		instance.LoginIfIP = tLoginIfIP
-		instance.MAC = tMac
+		return nil, err
 	}
+	instance.LoginIf = tLoginIf
+	instance.LoginIfIP = tLoginIfIP
+	instance.LoginIfMAC = tMac
+	instance.MAC = tMac
 	slog.Info(fmt.Sprintf("[%s] Use interface %s (%s|%s) to send request.", statusKey, instance.LoginIf, instance.LoginIfIP, instance.MAC))
+	applyMACOverride(instance, statusKey)
+	recordInstance(instance, statusKey)
+
+	if dhcp != nil {
+		instance.LeaseDNS = dhcp.Lease().DNS
+	}
+	applyBindOptions(instance, statusKey)
+
+	instance.restoreSession(statusKey)
+	return instance, nil
+}
+
+// 工作函数
+func Worker(cfg config.ConfigInstance, statusKey string, quitSender <-chan struct{}) {
+	slog.Info(fmt.Sprintf("[%s] Starting instance %s", statusKey, statusKey))
 
-	// 为空时提供默认值
-	if instance.UserAgent == "" {
-		instance.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36"
-		slog.Debug(fmt.Sprintf("[%s] Default user agent not set. Return to default '%s'", statusKey, instance.UserAgent))
+	err := runInstance(cfg, statusKey, func(cfg config.ConfigInstance, dhcp *nnet.DHCPClient) {
+		runWorker(cfg, statusKey, quitSender, dhcp)
+	})
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] Error running instance: %v", statusKey, err))
 	}
-	if instance.KAliveLink == "" {
-		instance.KAliveLink = "http://3.3.3.3"
-		slog.Debug(fmt.Sprintf("[%s] Default keep alive link not set. Return to default '%s'", statusKey, instance.KAliveLink))
+
+	WorkerStatusLock.Lock()
+	WorkerStatus[statusKey] = StateStopped
+	WorkerStatusLock.Unlock()
+}
+
+// Logout 一次性登出，优先使用保存的会话信息
+// 不创建或删除接口，也不获取地址，配置了 macvlan 时使用运行中的实例创建的子接口
+func Logout(cfg config.ConfigInstance, statusKey string) error {
+	if cfg.Macvlan != nil {
+		cfg.Interface = macvlanOptions(cfg).Name
+	}
+
+	ok := false
+	err := nnet.RunInNetns(cfg.Netns, func() error {
+		instance, err := newWorkerInstance(cfg, statusKey, nil)
+		if err != nil && cfg.Macvlan != nil {
+			return fmt.Errorf("macvlan %s is not usable, it exists only while the instance is running: %v", cfg.Interface, err)
+		} else if err != nil {
+			return fmt.Errorf("error parsing interface: %v", err)
+		}
+		defer instance.releaseBinding()
+		ok = doLogout(instance, statusKey)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("logout failed")
+	}
+	return nil
+}
+
+func runWorker(cfg config.ConfigInstance, statusKey string, quitSender <-chan struct{}, dhcp *nnet.DHCPClient) {
+	instance, err := newWorkerInstance(cfg, statusKey, dhcp)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] Error parsing interface: %v", statusKey, err))
+		return
+	}
+	// 退出后关闭实例的连接，不影响使用同一IP的其他实例
+	defer instance.releaseBinding()
+
+	// 内置 DHCP 客户端的租约变化
+	var leases <-chan nnet.DHCPLease
+	if dhcp != nil {
+		leases = dhcp.Updates()
 	}
 
 	// kAliveTicker := time.NewTicker(time.Duration(cfg.KeepAlive) * time.Second)
 	// defer kAliveTicker.Stop()
 	retry := 0
 
+	// 下一次检查登录的时间，等待期间仍处理退出信号与租约变化
+	wait := time.NewTimer(0)
+	defer wait.Stop()
+
 loop:
 	for {
 		quitSignal := false
@@ -287,24 +555,51 @@ loop:
 		case <-quitSender:
 			// 收到退出信号
 			quitSignal = true
-			doLogout(instance, statusKey)
+			// 租约丢失后没有可用的地址，也已无法在线
+			if instance.LoginIfIP != "" {
+				doLogout(instance, statusKey)
+			}
 			break loop
-		default:
+		case lease := <-leases:
+			// DHCP 租约变化
+			if lease.IP == nil {
+				// 地址已从接口移除，释放后等待新的租约再登录
+				slog.Warn(fmt.Sprintf("[%s] DHCP lease on %s expired, requesting a new one.", statusKey, instance.LoginIf))
+				instance.releaseBinding()
+				instance.LoginIfIP = ""
+				setWorkerStatus(statusKey, StateNotLoggedIn)
+				continue
+			}
+			if instance.LoginIfIP != lease.IP.String() {
+				slog.Info(fmt.Sprintf("[%s] DHCP lease on %s changed to %s.", statusKey, instance.LoginIf, lease.IP))
+			}
+			instance.LoginIfIP = lease.IP.String()
+			instance.LeaseDNS = lease.DNS
+			applyBindOptions(instance, statusKey)
+			// 立即以新地址检查登录
+			wait.Reset(0)
+		case <-wait.C:
 			// 自动更新默认网口
 			if instance.Interface == "" {
 				now_if, now_ip, now_mac, err := nnet.GetDefaultIfIP()
 				if err != nil {
 					slog.Error(fmt.Sprintf("[%s] Error parsing interface: failed to get default interface ip: %v", statusKey, err))
 				}
-				if instance.LoginIf != now_if || instance.LoginIfIP != now_ip || instance.MAC != now_mac {
+				if instance.LoginIf != now_if || instance.LoginIfIP != now_ip || instance.LoginIfMAC != now_mac {
 					slog.Info(fmt.Sprintf("[%s] Interface has upgrade to %s (%s|%s).", statusKey, now_if, now_ip, now_mac))
+					instance.LoginIf = now_if
+					instance.LoginIfIP = now_ip
+					instance.LoginIfMAC = now_mac
+					instance.MAC = now_mac
+					applyMACOverride(instance, statusKey)
+					applyBindOptions(instance, statusKey)
 				}
-				instance.LoginIf = now_if
-				instance.LoginIfIP = now_ip
-				instance.MAC = now_mac
 			}
 
-			if !quitSignal {
+			if instance.LoginIfIP == "" {
+				slog.Debug(fmt.Sprintf("[%s] Waiting for a DHCP lease on %s. Skip login.", statusKey, instance.LoginIf))
+				wait.Reset(time.Duration(cfg.KeepAlive) * time.Second)
+			} else if !quitSignal {
 				// 正常执行登录逻辑
 				if doLogin(instance, statusKey) {
 					retry = 0
@@ -318,17 +613,13 @@ loop:
 					WorkerStatus[statusKey] = StatePaused
 					WorkerStatusLock.Unlock()
 					slog.Error(fmt.Sprintf("[%s] reached max retries, stop 10 min.", statusKey))
-					time.Sleep(time.Duration(10) * time.Minute)
+					wait.Reset(time.Duration(10) * time.Minute)
 				} else {
-					time.Sleep(time.Duration(cfg.KeepAlive) * time.Second)
+					wait.Reset(time.Duration(cfg.KeepAlive) * time.Second)
 				}
 			} else {
 				slog.Debug(fmt.Sprintf("[%s] Quit signal received. Skip login.", statusKey))
 			}
 		}
 	}
-
-	WorkerStatusLock.Lock()
-	WorkerStatus[statusKey] = StateStopped
-	WorkerStatusLock.Unlock()
 }
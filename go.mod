@@ -2,7 +2,15 @@ module github.com/summonhim/gzgspd
 
 go 1.25.0
 
-require golang.org/x/sys v0.39.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/robertkrimen/otto v0.5.1
+	github.com/vishvananda/netlink v1.3.1
+	github.com/vishvananda/netns v0.0.5
+	golang.org/x/net v0.48.0
+	golang.org/x/sys v0.39.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	git.sr.ht/~jackmordaunt/go-toast v1.1.2 // indirect
@@ -21,10 +29,9 @@ require (
 	github.com/jackmordaunt/icns/v3 v3.0.1 // indirect
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646 // indirect
 	github.com/oxtoacart/bpool v0.0.0-20190530202638-03653db5a59c // indirect
-	github.com/robertkrimen/otto v0.5.1 // indirect
 	github.com/sergeymakinen/go-bmp v1.0.0 // indirect
 	github.com/sergeymakinen/go-ico v1.0.0-beta.0 // indirect
 	github.com/tadvi/systray v0.0.0-20190226123456-11a2b8fa57af // indirect
-	golang.org/x/text v0.4.0 // indirect
+	golang.org/x/text v0.32.0 // indirect
 	gopkg.in/sourcemap.v1 v1.0.5 // indirect
 )
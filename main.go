@@ -1,12 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"runtime"
-	"sync"
+	"slices"
 	"syscall"
 
 	"github.com/summonhim/gzgspd/config"
@@ -19,50 +20,159 @@ var (
 	BuildTime string = "0"
 )
 
-func runAsDaemon(ConfigFile string) error {
+func runAsDaemon(ConfigFile string, sets []string) error {
 	// 读取配置文件
-	cfg, err := config.LoadConfig(ConfigFile)
+	cfg, err := config.LoadConfig(ConfigFile, sets)
 	if err != nil {
 		return fmt.Errorf("Failed to load configuration file: %v", err)
 	}
 
-	// 初始化日志系统
+	// 初始化日志系统，日志等级可随配置重新加载
+	logLevel := &slog.LevelVar{}
+	logLevel.Set(slog.Level(cfg.LogLevel))
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.Level(cfg.LogLevel),
+		Level: logLevel,
 	})))
 	slog.Info(fmt.Sprintf("Starting GZGS portal daemon (%s)...", Version))
+	executor.SetStateDir(cfg.StateDir)
 
-	// 监听终止命令
-	executor.WorkerStatus = make(map[string]executor.WorkerState)
-	quitSender := make(chan struct{})
+	// 监听终止与重新加载命令
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	var wg sync.WaitGroup
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	for _, inst := range cfg.Instance {
-		key := inst.Username + "@" + nnet.GetKeyIfName(inst)
-		executor.WorkerStatus[key] = executor.StateStarting
+	supervisor := executor.NewSupervisor()
+	supervisor.Apply(cfg.Instance)
+
+	// 监视配置文件，失败时仍可通过 SIGHUP 重新加载
+	watcher := watchConfig(cfg)
+	defer func() {
+		if watcher != nil {
+			watcher.Close()
+		}
+	}()
 
-		wg.Add(1)
-		go func(ci config.ConfigInstance) {
-			defer wg.Done()
-			executor.Worker(ci, key, quitSender)
-		}(inst)
+	reload := func(reason string) {
+		newCfg, err := config.LoadConfig(ConfigFile, sets)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to reload configuration (%s), keeping the running configuration: %v", reason, err))
+			return
+		}
+		slog.Info(fmt.Sprintf("Reloading configuration (%s)...", reason))
+		logLevel.Set(slog.Level(newCfg.LogLevel))
+		executor.SetStateDir(newCfg.StateDir)
+		supervisor.Apply(newCfg.Instance)
+
+		// 被监视的文件有变化时重新监视
+		if !slices.Equal(newCfg.Sources, cfg.Sources) || !slices.Equal(newCfg.SourceDirs, cfg.SourceDirs) {
+			if watcher != nil {
+				watcher.Close()
+			}
+			watcher = watchConfig(newCfg)
+		}
+		cfg = newCfg
 	}
 
-	<-sigs
-	slog.Info("Caught termination signal, logging out...")
-	close(quitSender)
-	wg.Wait()
-	slog.Info("All instances stopped. Exiting...")
-	return nil
+	for {
+		var events <-chan struct{}
+		if watcher != nil {
+			events = watcher.Events()
+		}
+
+		select {
+		case sig := <-sigs:
+			if sig == syscall.SIGHUP {
+				reload("SIGHUP")
+				continue
+			}
+			slog.Info("Caught termination signal, logging out...")
+			supervisor.StopAll()
+			slog.Info("All instances stopped. Exiting...")
+			return nil
+		case <-events:
+			reload("file changed")
+		}
+	}
 }
 
-func testConfig(ConfigFile string) error {
-	_, err := config.LoadConfig(ConfigFile)
+// watchConfig 监视配置文件与 include 目录，失败时返回 nil
+func watchConfig(cfg *config.Config) *config.Watcher {
+	watcher, err := config.NewWatcher(cfg.Sources, cfg.SourceDirs)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to watch configuration files, reload with SIGHUP instead: %v", err))
+		return nil
+	}
+	return watcher
+}
+
+func testConfig(ConfigFile string, sets []string, print bool) error {
+	cfg, err := config.LoadConfig(ConfigFile, sets)
 	if err != nil {
 		return fmt.Errorf("%v", err)
 	}
+
+	// 打印合并后的配置，隐藏密码
+	if print {
+		data, err := json.MarshalIndent(cfg.Redacted(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	}
+	return nil
+}
+
+func logoutOnce(ConfigFile string, sets []string) error {
+	cfg, err := config.LoadConfig(ConfigFile, sets)
+	if err != nil {
+		return fmt.Errorf("Failed to load configuration file: %v", err)
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.Level(cfg.LogLevel),
+	})))
+	executor.SetStateDir(cfg.StateDir)
+	executor.WorkerStatus = make(map[string]executor.WorkerState)
+
+	failed := 0
+	for _, inst := range cfg.Instance {
+		key := inst.Key()
+		if err := executor.Logout(inst, key); err != nil {
+			slog.Error(fmt.Sprintf("[%s] %v", key, err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d instances failed to log out", failed, len(cfg.Instance))
+	}
+	return nil
+}
+
+// 使用 --record 录制的记录离线执行一次登录与登出
+func replayOnce(ConfigFile string, sets []string, dir string) error {
+	cfg, err := config.LoadConfig(ConfigFile, sets)
+	if err != nil {
+		return fmt.Errorf("Failed to load configuration file: %v", err)
+	}
+	if err := nnet.StartReplay(dir); err != nil {
+		return fmt.Errorf("Failed to load capture: %v", err)
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.Level(cfg.LogLevel),
+	})))
+	executor.WorkerStatus = make(map[string]executor.WorkerState)
+
+	failed := 0
+	for _, inst := range cfg.Instance {
+		key := inst.Key()
+		if err := executor.Replay(inst, key); err != nil {
+			slog.Error(fmt.Sprintf("[%s] %v", key, err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d instances failed to replay", failed, len(cfg.Instance))
+	}
 	return nil
 }
 
@@ -83,8 +193,14 @@ func main() {
 		os.Exit(0)
 	}
 
+	if flags.ActionSchema {
+		data, _ := json.MarshalIndent(config.Schema(), "", "  ")
+		fmt.Println(string(data))
+		os.Exit(0)
+	}
+
 	if flags.ActionTestConfig {
-		err := testConfig(flags.ConfigFile)
+		err := testConfig(flags.ConfigFile, flags.Sets, flags.ActionPrint)
 		if err != nil {
 			fmt.Printf("%s", err)
 			os.Exit(10)
@@ -94,7 +210,34 @@ func main() {
 		}
 	}
 
-	err := runAsDaemon(flags.ConfigFile)
+	if flags.ReplayDir != "" {
+		err := replayOnce(flags.ConfigFile, flags.Sets, flags.ReplayDir)
+		if err != nil {
+			fmt.Printf("%s", err)
+			os.Exit(1)
+		} else {
+			os.Exit(0)
+		}
+	}
+
+	if flags.RecordDir != "" {
+		if err := nnet.StartRecording(flags.RecordDir, Version); err != nil {
+			fmt.Printf("Failed to start recording: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if flags.ActionLogout {
+		err := logoutOnce(flags.ConfigFile, flags.Sets)
+		if err != nil {
+			fmt.Printf("%s", err)
+			os.Exit(1)
+		} else {
+			os.Exit(0)
+		}
+	}
+
+	err := runAsDaemon(flags.ConfigFile, flags.Sets)
 	if err != nil {
 		fmt.Printf("%s", err)
 		os.Exit(1)
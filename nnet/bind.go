@@ -0,0 +1,51 @@
+package nnet
+
+import (
+	"crypto/tls"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// BindOptions 通过本地IP发送请求时的附加选项
+type BindOptions struct {
+	DNS   []string    // 该IP所在链路的DNS服务器，为空时使用系统DNS服务器
+	Netns string      // 该IP所在的网络命名空间，为空时使用当前命名空间
+	TLS   *tls.Config // 连接 https 门户时使用，为空时使用默认配置（见 NewTLSConfig）
+	Proxy *url.URL    // http、https 或 socks5 代理，从该IP连接代理，为空时直接连接
+}
+
+// Binding 实例发送请求使用的本地IP与附加选项
+// 每个实例各自持有，多个实例使用同一IP时选项与连接互不影响；选项变化时创建新的 Binding
+type Binding struct {
+	ip   string
+	opts BindOptions
+
+	lock       sync.Mutex
+	transports map[time.Duration]cachedTransport
+}
+
+// NewBinding 创建从本地IP发送请求的绑定
+func NewBinding(localIP string, opts BindOptions) *Binding {
+	return &Binding{
+		ip:         localIP,
+		opts:       opts,
+		transports: make(map[time.Duration]cachedTransport),
+	}
+}
+
+// IP 返回绑定的本地IP
+func (b *Binding) IP() string {
+	return b.ip
+}
+
+// Close 在不再使用（如地址变化或实例退出）时丢弃缓存的 Transport 并关闭其空闲连接
+// 正在进行的请求不受影响
+func (b *Binding) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for timeout, cached := range b.transports {
+		cached.transport.CloseIdleConnections()
+		delete(b.transports, timeout)
+	}
+}
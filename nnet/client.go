@@ -0,0 +1,64 @@
+package nnet
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// 空闲连接保留的时长，门户通常在此之前关闭连接
+const transportIdleTimeout = 90 * time.Second
+
+// cachedTransport 缓存的 Transport，roundTripper 为录制时包装后的结果
+type cachedTransport struct {
+	transport    *http.Transport
+	roundTripper http.RoundTripper
+}
+
+// NewHttpClientBindIP 根据绑定的本地IP与选项创建HTTP客户端
+// 同一绑定与超时的客户端共用 Transport 以复用连接，直到绑定被关闭（见 Binding.Close）
+func NewHttpClientBindIP(bind *Binding, timeout time.Duration) (*http.Client, error) {
+	if bind == nil {
+		return nil, fmt.Errorf("no local IP to send requests from")
+	}
+
+	// 回放记录时不访问网络
+	if client := replayClient(bind.ip, timeout); client != nil {
+		return client, nil
+	}
+
+	rt, err := bind.transport(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: rt,
+		Timeout:   timeout,
+	}
+
+	return client, nil
+}
+
+// transport 返回绑定缓存的 Transport，没有时创建
+func (b *Binding) transport(timeout time.Duration) (http.RoundTripper, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if cached, ok := b.transports[timeout]; ok {
+		return cached.roundTripper, nil
+	}
+
+	transport, err := newTransportBindIP(b.ip, b.opts, timeout)
+	if err != nil {
+		return nil, err
+	}
+	cached := cachedTransport{
+		transport:    transport,
+		roundTripper: wrapTransport(b.ip, transport),
+	}
+	b.transports[timeout] = cached
+	return cached.roundTripper, nil
+}
@@ -0,0 +1,105 @@
+package nnet
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 同一IP上的两个实例各自缓存连接，一个实例退出后另一个仍复用原来的连接
+func TestBindingSharedIP(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	const localIP = "127.0.0.1"
+	a := NewBinding(localIP, BindOptions{})
+	defer a.Close()
+	b := NewBinding(localIP, BindOptions{})
+
+	get := func(bind *Binding) {
+		t.Helper()
+		client, err := NewHttpClientBindIP(bind, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	get(a)
+	get(a)
+	if n := conns.Load(); n != 1 {
+		t.Fatalf("%d connections for one instance, want 1", n)
+	}
+	get(b)
+	if n := conns.Load(); n != 2 {
+		t.Fatalf("%d connections for two instances, want 2", n)
+	}
+
+	// b 退出，a 的连接不受影响
+	b.Close()
+	get(a)
+	if n := conns.Load(); n != 2 {
+		t.Errorf("%d connections after the other instance exited, want 2", n)
+	}
+
+	// a 关闭后重新连接
+	a.Close()
+	get(a)
+	if n := conns.Load(); n != 3 {
+		t.Errorf("%d connections after closing, want 3", n)
+	}
+}
+
+// BenchmarkNewHttpClientBindIP 比较复用缓存的 Transport 与每次新建时一次门户请求的开销
+func BenchmarkNewHttpClientBindIP(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	bind := NewBinding("127.0.0.1", BindOptions{})
+	get := func(b *testing.B) {
+		client, err := NewHttpClientBindIP(bind, 5*time.Second)
+		if err != nil {
+			b.Fatal(err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		defer bind.Close()
+		for b.Loop() {
+			get(b)
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		for b.Loop() {
+			// 丢弃缓存的 Transport，每次请求新建连接
+			bind.Close()
+			get(b)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package nnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DHCP 报文类型
+const (
+	dhcpDiscover = 1
+	dhcpOffer    = 2
+	dhcpRequest  = 3
+	dhcpDecline  = 4
+	dhcpAck      = 5
+	dhcpNak      = 6
+	dhcpRelease  = 7
+)
+
+// DHCP 选项
+const (
+	dhcpOptSubnetMask   = 1
+	dhcpOptRouter       = 3
+	dhcpOptDNS          = 6
+	dhcpOptHostName     = 12
+	dhcpOptRequestedIP  = 50
+	dhcpOptLeaseTime    = 51
+	dhcpOptMessageType  = 53
+	dhcpOptServerID     = 54
+	dhcpOptParamRequest = 55
+	dhcpOptRenewalTime  = 58
+	dhcpOptRebindTime   = 59
+	dhcpOptClientID     = 61
+	dhcpOptEnd          = 255
+	dhcpOptPad          = 0
+)
+
+var dhcpMagicCookie = []byte{99, 130, 83, 99}
+
+// DHCPLease DHCP 租约
+type DHCPLease struct {
+	IP        net.IP
+	Mask      net.IPMask
+	Router    net.IP
+	DNS       []string
+	ServerID  net.IP
+	LeaseTime time.Duration
+	T1        time.Duration
+	T2        time.Duration
+	Acquired  time.Time
+}
+
+// dhcpMessage 解析后的 DHCP 报文
+type dhcpMessage struct {
+	Op      byte
+	Xid     uint32
+	Flags   uint16
+	CIAddr  net.IP
+	YIAddr  net.IP
+	CHAddr  net.HardwareAddr
+	Options map[byte][]byte
+}
+
+// marshal 编码 DHCP 报文
+func (m *dhcpMessage) marshal() []byte {
+	buf := make([]byte, 240, 300)
+	buf[0] = m.Op
+	buf[1] = 1 // 以太网
+	buf[2] = 6
+	binary.BigEndian.PutUint32(buf[4:8], m.Xid)
+	binary.BigEndian.PutUint16(buf[10:12], m.Flags)
+	if ip := m.CIAddr.To4(); ip != nil {
+		copy(buf[12:16], ip)
+	}
+	if ip := m.YIAddr.To4(); ip != nil {
+		copy(buf[16:20], ip)
+	}
+	copy(buf[28:44], m.CHAddr)
+	copy(buf[236:240], dhcpMagicCookie)
+
+	// 报文类型放在首位，便于服务器识别
+	if v, ok := m.Options[dhcpOptMessageType]; ok {
+		buf = append(buf, dhcpOptMessageType, byte(len(v)))
+		buf = append(buf, v...)
+	}
+	for code, v := range m.Options {
+		if code == dhcpOptMessageType {
+			continue
+		}
+		buf = append(buf, code, byte(len(v)))
+		buf = append(buf, v...)
+	}
+	buf = append(buf, dhcpOptEnd)
+
+	// 部分服务器要求报文不短于 BOOTP 最小长度
+	for len(buf) < 300 {
+		buf = append(buf, dhcpOptPad)
+	}
+	return buf
+}
+
+// parseDHCPMessage 解析 DHCP 报文
+func parseDHCPMessage(b []byte) (*dhcpMessage, error) {
+	if len(b) < 240 || !bytes.Equal(b[236:240], dhcpMagicCookie) {
+		return nil, fmt.Errorf("not a dhcp message")
+	}
+
+	m := &dhcpMessage{
+		Op:      b[0],
+		Xid:     binary.BigEndian.Uint32(b[4:8]),
+		Flags:   binary.BigEndian.Uint16(b[10:12]),
+		CIAddr:  net.IP(append([]byte(nil), b[12:16]...)),
+		YIAddr:  net.IP(append([]byte(nil), b[16:20]...)),
+		CHAddr:  net.HardwareAddr(append([]byte(nil), b[28:28+min(int(b[2]), 16)]...)),
+		Options: make(map[byte][]byte),
+	}
+
+	opts := b[240:]
+	for i := 0; i < len(opts); {
+		code := opts[i]
+		if code == dhcpOptEnd {
+			break
+		}
+		if code == dhcpOptPad {
+			i++
+			continue
+		}
+		if i+1 >= len(opts) || i+2+int(opts[i+1]) > len(opts) {
+			return nil, fmt.Errorf("truncated dhcp option %d", code)
+		}
+		// 同一选项多次出现时按 RFC 3396 拼接
+		m.Options[code] = append(m.Options[code], opts[i+2:i+2+int(opts[i+1])]...)
+		i += 2 + int(opts[i+1])
+	}
+	return m, nil
+}
+
+// messageType 返回报文类型
+func (m *dhcpMessage) messageType() byte {
+	if v := m.Options[dhcpOptMessageType]; len(v) == 1 {
+		return v[0]
+	}
+	return 0
+}
+
+// newDHCPRequest 构造客户端报文
+func newDHCPRequest(msgType byte, xid uint32, mac net.HardwareAddr, hostname string) *dhcpMessage {
+	m := &dhcpMessage{
+		Op:     1,
+		Xid:    xid,
+		Flags:  0x8000, // 要求服务器广播应答，未配置地址时也能收到
+		CHAddr: mac,
+		Options: map[byte][]byte{
+			dhcpOptMessageType: {msgType},
+			dhcpOptClientID:    append([]byte{1}, mac...),
+			dhcpOptParamRequest: {
+				dhcpOptSubnetMask, dhcpOptRouter, dhcpOptDNS,
+				dhcpOptLeaseTime, dhcpOptServerID, dhcpOptRenewalTime, dhcpOptRebindTime,
+			},
+		},
+	}
+	if hostname != "" {
+		m.Options[dhcpOptHostName] = []byte(hostname)
+	}
+	return m
+}
+
+// lease 从 ACK 报文中提取租约
+func (m *dhcpMessage) lease() (*DHCPLease, error) {
+	ip := m.YIAddr.To4()
+	if ip == nil || ip.IsUnspecified() {
+		return nil, fmt.Errorf("no address offered")
+	}
+
+	l := &DHCPLease{
+		IP:       ip,
+		Mask:     net.IPMask(m.Options[dhcpOptSubnetMask]),
+		ServerID: net.IP(m.Options[dhcpOptServerID]),
+		Acquired: time.Now(),
+	}
+	if len(l.Mask) != 4 {
+		l.Mask = ip.DefaultMask()
+	}
+	if r := m.Options[dhcpOptRouter]; len(r) >= 4 {
+		l.Router = net.IP(r[:4])
+	}
+	for d := m.Options[dhcpOptDNS]; len(d) >= 4; d = d[4:] {
+		l.DNS = append(l.DNS, net.IP(d[:4]).String())
+	}
+
+	l.LeaseTime = optSeconds(m.Options[dhcpOptLeaseTime], time.Hour)
+	l.T1 = optSeconds(m.Options[dhcpOptRenewalTime], l.LeaseTime/2)
+	l.T2 = optSeconds(m.Options[dhcpOptRebindTime], l.LeaseTime*7/8)
+	return l, nil
+}
+
+// optSeconds 解析以秒为单位的 32 位选项，缺失时返回默认值
+func optSeconds(v []byte, def time.Duration) time.Duration {
+	if len(v) != 4 {
+		return def
+	}
+	return time.Duration(binary.BigEndian.Uint32(v)) * time.Second
+}
@@ -0,0 +1,366 @@
+//go:build linux
+
+package nnet
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// DHCPClient 最小 DHCPv4 客户端，为 daemon 管理的接口获取并续约地址
+type DHCPClient struct {
+	ifName   string
+	hostname string
+	link     netlink.Link
+	handle   *netlink.Handle
+	conn     net.PacketConn
+	table    int
+
+	lock    sync.Mutex
+	lease   *DHCPLease
+	updates chan DHCPLease
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+// StartDHCP 在接口上获取 DHCP 租约并应用地址、路由，之后在后台续约
+// 需在接口所在的网络命名空间内调用。租约变化（包括丢失）通过 Updates 通知
+func StartDHCP(ifName string) (*DHCPClient, error) {
+	handle, err := netlink.NewHandle()
+	if err != nil {
+		return nil, err
+	}
+	link, err := handle.LinkByName(ifName)
+	if err != nil {
+		handle.Close()
+		return nil, fmt.Errorf("interface %s not found: %v", ifName, err)
+	}
+	conn, err := dhcpListen(ifName, 68)
+	if err != nil {
+		handle.Close()
+		return nil, fmt.Errorf("failed to listen dhcp on %s: %v", ifName, err)
+	}
+	hostname, _ := os.Hostname()
+
+	c := &DHCPClient{
+		ifName:   ifName,
+		hostname: hostname,
+		link:     link,
+		handle:   handle,
+		conn:     conn,
+		table:    1000 + link.Attrs().Index,
+		updates:  make(chan DHCPLease, 1),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	var lease *DHCPLease
+	for i := 0; i < 3; i++ {
+		if lease, err = c.acquire(); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	if err := c.apply(lease); err != nil {
+		c.close()
+		return nil, err
+	}
+
+	go c.run()
+	return c, nil
+}
+
+// Lease 返回当前租约
+func (c *DHCPClient) Lease() DHCPLease {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.lease == nil {
+		return DHCPLease{}
+	}
+	return *c.lease
+}
+
+// Updates 返回租约变化通知，租约丢失时收到 IP 为空的租约
+func (c *DHCPClient) Updates() <-chan DHCPLease {
+	return c.updates
+}
+
+// Stop 停止续约，释放租约并移除地址与路由
+func (c *DHCPClient) Stop() {
+	close(c.quit)
+	<-c.done
+
+	if lease := c.currentLease(); lease != nil {
+		release := newDHCPRequest(dhcpRelease, randomXid(), c.link.Attrs().HardwareAddr, "")
+		release.CIAddr = lease.IP
+		release.Options[dhcpOptServerID] = lease.ServerID.To4()
+		delete(release.Options, dhcpOptParamRequest)
+		c.send(release, lease.ServerID)
+		c.unapply(lease)
+	}
+	c.close()
+}
+
+func (c *DHCPClient) close() {
+	c.conn.Close()
+	c.handle.Close()
+}
+
+func (c *DHCPClient) currentLease() *DHCPLease {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lease
+}
+
+// run 按 T1、T2 与租期续约，租约过期后重新获取
+func (c *DHCPClient) run() {
+	defer close(c.done)
+
+	retry := time.Duration(0)
+	for {
+		lease := c.currentLease()
+		var wait time.Duration
+		switch {
+		case lease == nil:
+			wait = retry
+		case retry > 0:
+			wait = retry
+		default:
+			wait = time.Until(lease.Acquired.Add(lease.T1))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-c.quit:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		var next *DHCPLease
+		var err error
+		var deadline time.Time
+		switch {
+		case lease != nil && time.Now().Before(lease.Acquired.Add(lease.T2)):
+			// 续约：单播至原服务器
+			next, err = c.renew(lease, lease.ServerID)
+			deadline = lease.Acquired.Add(lease.T2)
+		case lease != nil && time.Now().Before(lease.Acquired.Add(lease.LeaseTime)):
+			// 重新绑定：广播至任意服务器
+			next, err = c.renew(lease, net.IPv4bcast)
+			deadline = lease.Acquired.Add(lease.LeaseTime)
+		default:
+			// 租约已过期，移除地址后重新获取
+			if lease != nil {
+				c.unapply(lease)
+				c.notify(DHCPLease{})
+			}
+			next, err = c.acquire()
+		}
+
+		if err != nil {
+			// 在截止时间前折半重试，最短 10 秒
+			retry = max(time.Until(deadline)/2, 10*time.Second)
+			continue
+		}
+		retry = 0
+		if err := c.apply(next); err != nil {
+			retry = 10 * time.Second
+			continue
+		}
+		c.notify(*next)
+	}
+}
+
+// notify 推送租约变化，只保留最新一次
+func (c *DHCPClient) notify(lease DHCPLease) {
+	select {
+	case <-c.updates:
+	default:
+	}
+	c.updates <- lease
+}
+
+// acquire 通过 DISCOVER、OFFER、REQUEST、ACK 获取新租约
+func (c *DHCPClient) acquire() (*DHCPLease, error) {
+	mac := c.link.Attrs().HardwareAddr
+	xid := randomXid()
+
+	discover := newDHCPRequest(dhcpDiscover, xid, mac, c.hostname)
+	offer, err := c.exchange(discover, net.IPv4bcast, dhcpOffer)
+	if err != nil {
+		return nil, fmt.Errorf("no dhcp offer on %s: %v", c.ifName, err)
+	}
+
+	request := newDHCPRequest(dhcpRequest, xid, mac, c.hostname)
+	request.Options[dhcpOptRequestedIP] = offer.YIAddr.To4()
+	request.Options[dhcpOptServerID] = offer.Options[dhcpOptServerID]
+	ack, err := c.exchange(request, net.IPv4bcast, dhcpAck)
+	if err != nil {
+		return nil, fmt.Errorf("dhcp request on %s failed: %v", c.ifName, err)
+	}
+	return ack.lease()
+}
+
+// renew 续约或重新绑定现有租约
+func (c *DHCPClient) renew(lease *DHCPLease, dst net.IP) (*DHCPLease, error) {
+	request := newDHCPRequest(dhcpRequest, randomXid(), c.link.Attrs().HardwareAddr, c.hostname)
+	request.CIAddr = lease.IP
+	request.Flags = 0
+	ack, err := c.exchange(request, dst, dhcpAck)
+	if err != nil {
+		return nil, fmt.Errorf("dhcp renew on %s failed: %v", c.ifName, err)
+	}
+	return ack.lease()
+}
+
+// exchange 发送请求并等待指定类型的应答，收到 NAK 时返回错误
+func (c *DHCPClient) exchange(req *dhcpMessage, dst net.IP, want byte) (*dhcpMessage, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		if err := c.send(req, dst); err != nil {
+			return nil, err
+		}
+
+		c.conn.SetReadDeadline(time.Now().Add(4 * time.Second))
+		buf := make([]byte, 1500)
+		for {
+			n, _, err := c.conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			reply, err := parseDHCPMessage(buf[:n])
+			if err != nil || reply.Op != 2 || reply.Xid != req.Xid {
+				continue
+			}
+			switch reply.messageType() {
+			case want:
+				return reply, nil
+			case dhcpNak:
+				return nil, fmt.Errorf("server declined request")
+			}
+		}
+	}
+	return nil, fmt.Errorf("timed out")
+}
+
+// send 发送报文至服务器 67 端口
+func (c *DHCPClient) send(m *dhcpMessage, dst net.IP) error {
+	_, err := c.conn.WriteTo(m.marshal(), &net.UDPAddr{IP: dst, Port: 67})
+	return err
+}
+
+// apply 应用租约的地址与路由
+// 默认路由写入独立路由表并以源地址策略路由引用，不影响系统默认路由
+func (c *DHCPClient) apply(lease *DHCPLease) error {
+	if old := c.currentLease(); old != nil && !old.IP.Equal(lease.IP) {
+		c.unapply(old)
+	}
+
+	ipNet := &net.IPNet{IP: lease.IP, Mask: lease.Mask}
+	addr := &netlink.Addr{IPNet: ipNet}
+	if err := c.handle.AddrReplace(c.link, addr); err != nil {
+		return fmt.Errorf("failed to set address %s on %s: %v", ipNet, c.ifName, err)
+	}
+
+	subnet := &net.IPNet{IP: lease.IP.Mask(lease.Mask), Mask: lease.Mask}
+	if err := c.handle.RouteReplace(&netlink.Route{
+		LinkIndex: c.link.Attrs().Index,
+		Dst:       subnet,
+		Src:       lease.IP,
+		Scope:     netlink.SCOPE_LINK,
+		Table:     c.table,
+	}); err != nil {
+		return fmt.Errorf("failed to set subnet route on %s: %v", c.ifName, err)
+	}
+	if lease.Router != nil {
+		if err := c.handle.RouteReplace(&netlink.Route{
+			LinkIndex: c.link.Attrs().Index,
+			Gw:        lease.Router,
+			Table:     c.table,
+		}); err != nil {
+			return fmt.Errorf("failed to set default route on %s: %v", c.ifName, err)
+		}
+	}
+
+	rule := c.rule(lease)
+	c.handle.RuleDel(rule)
+	if err := c.handle.RuleAdd(rule); err != nil {
+		return fmt.Errorf("failed to add routing rule for %s: %v", lease.IP, err)
+	}
+
+	c.lock.Lock()
+	c.lease = lease
+	c.lock.Unlock()
+	return nil
+}
+
+// unapply 移除租约的地址、路由与策略
+func (c *DHCPClient) unapply(lease *DHCPLease) {
+	c.handle.RuleDel(c.rule(lease))
+	if lease.Router != nil {
+		c.handle.RouteDel(&netlink.Route{LinkIndex: c.link.Attrs().Index, Gw: lease.Router, Table: c.table})
+	}
+	c.handle.RouteDel(&netlink.Route{
+		LinkIndex: c.link.Attrs().Index,
+		Dst:       &net.IPNet{IP: lease.IP.Mask(lease.Mask), Mask: lease.Mask},
+		Scope:     netlink.SCOPE_LINK,
+		Table:     c.table,
+	})
+	c.handle.AddrDel(c.link, &netlink.Addr{IPNet: &net.IPNet{IP: lease.IP, Mask: lease.Mask}})
+
+	c.lock.Lock()
+	c.lease = nil
+	c.lock.Unlock()
+}
+
+// rule 返回租约地址的源地址策略
+func (c *DHCPClient) rule(lease *DHCPLease) *netlink.Rule {
+	rule := netlink.NewRule()
+	rule.Src = &net.IPNet{IP: lease.IP, Mask: net.CIDRMask(32, 32)}
+	rule.Table = c.table
+	rule.Priority = c.table
+	return rule
+}
+
+// dhcpListen 在接口上监听 UDP 端口，客户端为 68
+// 绑定到接口后，多个实例可在不同接口上同时运行
+func dhcpListen(ifName string, port int) (net.PacketConn, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.IPPROTO_UDP)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), "dhcp-"+ifName)
+	defer f.Close()
+
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); err != nil {
+		return nil, err
+	}
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_BROADCAST, 1); err != nil {
+		return nil, err
+	}
+	if err := unix.BindToDevice(fd, ifName); err != nil {
+		return nil, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrInet4{Port: port}); err != nil {
+		return nil, err
+	}
+
+	return net.FilePacketConn(f)
+}
+
+func randomXid() uint32 {
+	var b [4]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint32(b[:])
+}
@@ -0,0 +1,208 @@
+//go:build linux
+
+package nnet
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vishvananda/netlink"
+)
+
+// testDHCPServer 只分配一个地址的 DHCP 服务器
+type testDHCPServer struct {
+	conn     net.PacketConn
+	serverIP net.IP
+	offerIP  net.IP
+	lease    uint32
+	t1       uint32
+
+	lock     sync.Mutex
+	received []byte // 收到的报文类型
+}
+
+func (s *testDHCPServer) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req, err := parseDHCPMessage(buf[:n])
+		if err != nil || req.Op != 1 {
+			continue
+		}
+		s.lock.Lock()
+		s.received = append(s.received, req.messageType())
+		s.lock.Unlock()
+
+		var replyType byte
+		switch req.messageType() {
+		case dhcpDiscover:
+			replyType = dhcpOffer
+		case dhcpRequest:
+			replyType = dhcpAck
+		default:
+			continue
+		}
+		seconds := func(v uint32) []byte {
+			return binary.BigEndian.AppendUint32(nil, v)
+		}
+		reply := &dhcpMessage{
+			Op:     2,
+			Xid:    req.Xid,
+			Flags:  req.Flags,
+			YIAddr: s.offerIP,
+			CHAddr: req.CHAddr,
+			Options: map[byte][]byte{
+				dhcpOptMessageType: {replyType},
+				dhcpOptServerID:    s.serverIP.To4(),
+				dhcpOptSubnetMask:  net.CIDRMask(24, 32),
+				dhcpOptRouter:      s.serverIP.To4(),
+				dhcpOptDNS:         append(s.serverIP.To4(), 223, 5, 5, 5),
+				dhcpOptLeaseTime:   seconds(s.lease),
+				dhcpOptRenewalTime: seconds(s.t1),
+			},
+		}
+		// 续约时单播应答，否则广播
+		dst := net.IPv4bcast
+		if !req.CIAddr.IsUnspecified() {
+			dst = req.CIAddr
+		}
+		s.conn.WriteTo(reply.marshal(), &net.UDPAddr{IP: dst, Port: 68})
+	}
+}
+
+func (s *testDHCPServer) messages() []byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]byte(nil), s.received...)
+}
+
+// newTestDHCPLink 在两个新的网络命名空间之间创建 veth，服务器一端运行 DHCP 服务器
+// 返回客户端所在的命名空间与接口名称
+func newTestDHCPLink(t *testing.T) (string, string, *testDHCPServer) {
+	t.Helper()
+	clientNs := newTestNetns(t)
+	serverNs := newTestNetns(t)
+
+	const clientIf, serverIf = "dhcpc0", "dhcps0"
+	err := RunInNetns(clientNs, func() error {
+		veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: clientIf}, PeerName: serverIf}
+		if err := netlink.LinkAdd(veth); err != nil {
+			return err
+		}
+		if err := netlink.LinkSetUp(veth); err != nil {
+			return err
+		}
+		peer, err := netlink.LinkByName(serverIf)
+		if err != nil {
+			return err
+		}
+		ns, err := os.Open(serverNs)
+		if err != nil {
+			return err
+		}
+		defer ns.Close()
+		return netlink.LinkSetNsFd(peer, int(ns.Fd()))
+	})
+	if err != nil {
+		t.Skipf("cannot create veth pair: %v", err)
+	}
+
+	server := &testDHCPServer{
+		serverIP: net.IPv4(10, 99, 0, 1).To4(),
+		offerIP:  net.IPv4(10, 99, 0, 50).To4(),
+		lease:    60,
+		t1:       1,
+	}
+	err = RunInNetns(serverNs, func() error {
+		peer, err := netlink.LinkByName(serverIf)
+		if err != nil {
+			return err
+		}
+		addr := &netlink.Addr{IPNet: &net.IPNet{IP: server.serverIP, Mask: net.CIDRMask(24, 32)}}
+		if err := netlink.AddrAdd(peer, addr); err != nil {
+			return err
+		}
+		if err := netlink.LinkSetUp(peer); err != nil {
+			return err
+		}
+		server.conn, err = dhcpListen(serverIf, 67)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.serve()
+	t.Cleanup(func() { server.conn.Close() })
+	return clientNs, clientIf, server
+}
+
+func TestDHCPClient(t *testing.T) {
+	ns, ifName, server := newTestDHCPLink(t)
+
+	var client *DHCPClient
+	err := RunInNetns(ns, func() error {
+		var err error
+		client, err = StartDHCP(ifName)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lease := client.Lease()
+	if !lease.IP.Equal(server.offerIP) || !lease.Router.Equal(server.serverIP) || lease.LeaseTime != time.Minute || lease.T1 != time.Second {
+		t.Errorf("Lease() = %+v", lease)
+	}
+	if len(lease.DNS) != 2 || lease.DNS[0] != "10.99.0.1" || lease.DNS[1] != "223.5.5.5" {
+		t.Errorf("lease DNS = %v", lease.DNS)
+	}
+	hasAddr := func() bool {
+		var found bool
+		RunInNetns(ns, func() error {
+			link, err := netlink.LinkByName(ifName)
+			if err != nil {
+				return err
+			}
+			addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+			for _, addr := range addrs {
+				found = found || addr.IP.Equal(server.offerIP)
+			}
+			return err
+		})
+		return found
+	}
+	if !hasAddr() {
+		t.Errorf("address %s not set on %s", server.offerIP, ifName)
+	}
+
+	// T1 后续约
+	select {
+	case update := <-client.Updates():
+		if !update.IP.Equal(server.offerIP) {
+			t.Errorf("renewed lease = %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("lease was not renewed")
+	}
+
+	RunInNetns(ns, func() error {
+		client.Stop()
+		return nil
+	})
+	if hasAddr() {
+		t.Errorf("address %s left on %s after Stop", server.offerIP, ifName)
+	}
+	time.Sleep(100 * time.Millisecond)
+	msgs := server.messages()
+	want := []byte{dhcpDiscover, dhcpRequest, dhcpRequest, dhcpRelease}
+	if len(msgs) < len(want) || msgs[0] != dhcpDiscover || msgs[1] != dhcpRequest || msgs[len(msgs)-1] != dhcpRelease {
+		t.Errorf("server received %v, want %v", msgs, want)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package nnet
+
+import "fmt"
+
+// DHCPClient 非 Linux 平台不支持内置 DHCP 客户端
+type DHCPClient struct{}
+
+// StartDHCP 非 Linux 平台不支持内置 DHCP 客户端
+func StartDHCP(ifName string) (*DHCPClient, error) {
+	return nil, fmt.Errorf("built-in dhcp client is only supported on linux")
+}
+
+// Lease 返回当前租约
+func (c *DHCPClient) Lease() DHCPLease {
+	return DHCPLease{}
+}
+
+// Updates 返回租约变化通知
+func (c *DHCPClient) Updates() <-chan DHCPLease {
+	return nil
+}
+
+// Stop 停止续约
+func (c *DHCPClient) Stop() {}
@@ -3,8 +3,7 @@ package nnet
 import (
 	"fmt"
 	"net"
-
-	"github.com/summonhim/gzgspd/config"
+	"strings"
 )
 
 // GetIfIP 传入接口名称，返回 IPv4 地址
@@ -88,13 +87,39 @@ func GetIPMAC(ip string) (string, error) {
 	return "", fmt.Errorf("no interface found for ip")
 }
 
-// GetKeyIfName 从配置中获取接口字符串，为空则为Auto
-func GetKeyIfName(instance config.ConfigInstance) string {
-	var keyIfName string
-	if keyIfName == "" {
-		keyIfName = "Auto"
-	} else {
-		keyIfName = instance.Interface
+// GetIPIfName 根据本机的 IP 地址获取对应的网卡名称
+func GetIPIfName(ip string) (string, error) {
+	netIP := net.ParseIP(ip)
+	if netIP == nil {
+		return "", fmt.Errorf("invalid ip address")
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if ok && ipNet.IP.Equal(netIP) {
+				return iface.Name, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no interface found for ip")
+}
+
+// EqualMAC 比较两个 MAC 地址，忽略大小写与分隔符（":"、"-"、"." 或无分隔符）
+func EqualMAC(a string, b string) bool {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(s))
 	}
-	return keyIfName
+	return a != "" && normalize(a) == normalize(b)
 }
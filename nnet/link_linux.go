@@ -0,0 +1,44 @@
+//go:build linux
+
+package nnet
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+)
+
+// SetIfMAC 通过 netlink 设置接口的 MAC 地址，返回原 MAC 地址
+// 部分驱动要求接口关闭后才能修改，此时先关闭接口，修改后重新启用
+func SetIfMAC(ifName string, mac string) (string, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return "", fmt.Errorf("invalid mac address %s: %v", mac, err)
+	}
+
+	link, err := netlink.LinkByName(ifName)
+	if err != nil {
+		return "", fmt.Errorf("interface %s not found: %v", ifName, err)
+	}
+	old := link.Attrs().HardwareAddr.String()
+	if old == hw.String() {
+		return old, nil
+	}
+
+	if err := netlink.LinkSetHardwareAddr(link, hw); err == nil {
+		return old, nil
+	}
+
+	if err := netlink.LinkSetDown(link); err != nil {
+		return "", fmt.Errorf("failed to set %s down: %v", ifName, err)
+	}
+	err = netlink.LinkSetHardwareAddr(link, hw)
+	if upErr := netlink.LinkSetUp(link); upErr != nil && err == nil {
+		err = upErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to set %s mac to %s: %v", ifName, mac, err)
+	}
+	return old, nil
+}
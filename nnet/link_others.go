@@ -0,0 +1,10 @@
+//go:build !linux
+
+package nnet
+
+import "fmt"
+
+// SetIfMAC 非 Linux 平台不支持修改接口 MAC 地址
+func SetIfMAC(ifName string, mac string) (string, error) {
+	return "", fmt.Errorf("setting interface mac is only supported on linux")
+}
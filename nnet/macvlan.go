@@ -0,0 +1,30 @@
+package nnet
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+)
+
+// MacvlanOptions macvlan 子接口参数
+type MacvlanOptions struct {
+	Parent string // 父接口
+	Name   string // 子接口名称
+	MAC    string // 子接口 MAC 地址
+	Mode   string // macvlan 模式：bridge、private、vepa、passthru、source
+}
+
+// GenerateMAC 根据种子生成固定的本地管理单播 MAC 地址
+// 同一种子始终得到同一地址，重启后 DHCP 租约与门户绑定不变
+func GenerateMAC(seed string) string {
+	sum := sha256.Sum256([]byte("gzgspd-mac:" + seed))
+	mac := net.HardwareAddr(sum[:6])
+	mac[0] = (mac[0] | 0x02) & 0xfe
+	return mac.String()
+}
+
+// GenerateIfName 根据种子生成固定的子接口名称，长度不超过 IFNAMSIZ
+func GenerateIfName(seed string) string {
+	sum := sha256.Sum256([]byte("gzgspd-if:" + seed))
+	return fmt.Sprintf("gz%x", sum[:4])
+}
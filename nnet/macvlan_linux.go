@@ -0,0 +1,114 @@
+//go:build linux
+
+package nnet
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+var macvlanModes = map[string]netlink.MacvlanMode{
+	"":         netlink.MACVLAN_MODE_BRIDGE,
+	"bridge":   netlink.MACVLAN_MODE_BRIDGE,
+	"private":  netlink.MACVLAN_MODE_PRIVATE,
+	"vepa":     netlink.MACVLAN_MODE_VEPA,
+	"passthru": netlink.MACVLAN_MODE_PASSTHRU,
+	"source":   netlink.MACVLAN_MODE_SOURCE,
+}
+
+// netlinkHandle 获取指定网络命名空间的 netlink 句柄，ns 为空时使用当前命名空间
+func netlinkHandle(ns string) (*netlink.Handle, error) {
+	if ns == "" {
+		return netlink.NewHandle()
+	}
+
+	nsHandle, err := netns.GetFromPath(NetnsPath(ns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %s: %v", ns, err)
+	}
+	defer nsHandle.Close()
+
+	return netlink.NewHandleAt(nsHandle)
+}
+
+// CreateMacvlan 在父接口上创建 macvlan 子接口并启用
+// ns 不为空时子接口创建于该网络命名空间内，父接口须位于当前命名空间
+// 若同一父接口上已有同名 macvlan（如上次异常退出遗留），先将其删除；同名的其他接口返回错误
+func CreateMacvlan(opts MacvlanOptions, ns string) error {
+	mode, ok := macvlanModes[opts.Mode]
+	if !ok {
+		return fmt.Errorf("unknown macvlan mode: %s", opts.Mode)
+	}
+
+	mac, err := net.ParseMAC(opts.MAC)
+	if err != nil {
+		return fmt.Errorf("invalid mac address %s: %v", opts.MAC, err)
+	}
+
+	parent, err := netlink.LinkByName(opts.Parent)
+	if err != nil {
+		return fmt.Errorf("parent interface %s not found: %v", opts.Parent, err)
+	}
+
+	h, err := netlinkHandle(ns)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	// 只删除同一父接口上遗留的 macvlan，不动其他同名接口
+	if old, err := h.LinkByName(opts.Name); err == nil {
+		if old.Type() != "macvlan" || old.Attrs().ParentIndex != parent.Attrs().Index {
+			return fmt.Errorf("interface %s already exists and is not a macvlan on %s", opts.Name, opts.Parent)
+		}
+		if err := h.LinkDel(old); err != nil {
+			return fmt.Errorf("failed to remove stale interface %s: %v", opts.Name, err)
+		}
+	}
+
+	attrs := netlink.NewLinkAttrs()
+	attrs.Name = opts.Name
+	attrs.ParentIndex = parent.Attrs().Index
+	attrs.HardwareAddr = mac
+	if ns != "" {
+		nsHandle, err := netns.GetFromPath(NetnsPath(ns))
+		if err != nil {
+			return fmt.Errorf("failed to open netns %s: %v", ns, err)
+		}
+		defer nsHandle.Close()
+		attrs.Namespace = netlink.NsFd(nsHandle)
+	}
+
+	link := &netlink.Macvlan{LinkAttrs: attrs, Mode: mode}
+	if err := netlink.LinkAdd(link); err != nil {
+		return fmt.Errorf("failed to create macvlan %s: %v", opts.Name, err)
+	}
+
+	created, err := h.LinkByName(opts.Name)
+	if err != nil {
+		return fmt.Errorf("macvlan %s not found after creation: %v", opts.Name, err)
+	}
+	if err := h.LinkSetUp(created); err != nil {
+		return fmt.Errorf("failed to set %s up: %v", opts.Name, err)
+	}
+
+	return nil
+}
+
+// DeleteMacvlan 删除 CreateMacvlan 创建的子接口
+func DeleteMacvlan(name string, ns string) error {
+	h, err := netlinkHandle(ns)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	link, err := h.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("interface %s not found: %v", name, err)
+	}
+	return h.LinkDel(link)
+}
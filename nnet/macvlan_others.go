@@ -0,0 +1,15 @@
+//go:build !linux
+
+package nnet
+
+import "fmt"
+
+// CreateMacvlan 非 Linux 平台不支持 macvlan
+func CreateMacvlan(opts MacvlanOptions, ns string) error {
+	return fmt.Errorf("macvlan is only supported on linux")
+}
+
+// DeleteMacvlan 非 Linux 平台不支持 macvlan
+func DeleteMacvlan(name string, ns string) error {
+	return fmt.Errorf("macvlan is only supported on linux")
+}
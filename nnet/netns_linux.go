@@ -0,0 +1,71 @@
+//go:build linux
+
+package nnet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// NetnsPath 将网络命名空间名称转换为路径
+// 包含 "/" 时视为路径（如 /proc/<pid>/ns/net），否则视为 ip netns 创建的命名空间名称
+func NetnsPath(ns string) string {
+	if strings.Contains(ns, "/") {
+		return ns
+	}
+	if _, err := os.Stat(filepath.Join("/run/netns", ns)); err == nil {
+		return filepath.Join("/run/netns", ns)
+	}
+	return filepath.Join("/var/run/netns", ns)
+}
+
+// RunInNetns 锁定当前 OS 线程并切换到指定网络命名空间执行 fn，结束后切换回原命名空间
+// ns 为空时直接执行 fn
+func RunInNetns(ns string, fn func() error) error {
+	if ns == "" {
+		return fn()
+	}
+
+	target, err := os.Open(NetnsPath(ns))
+	if err != nil {
+		return fmt.Errorf("failed to open netns %s: %v", ns, err)
+	}
+	defer target.Close()
+
+	runtime.LockOSThread()
+
+	origin, err := os.Open(fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), unix.Gettid()))
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to open current netns: %v", err)
+	}
+	defer origin.Close()
+
+	if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to enter netns %s: %v", ns, err)
+	}
+
+	fnErr := fn()
+
+	// 切换失败时不解锁，线程随 goroutine 结束而销毁，避免污染其他 goroutine
+	if err := unix.Setns(int(origin.Fd()), unix.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("failed to restore netns: %v", err)
+	}
+	runtime.UnlockOSThread()
+
+	return fnErr
+}
+
+// GetNetnsDNS 获取 ip netns 为命名空间准备的 resolv.conf 中的 DNS 服务器
+func GetNetnsDNS(ns string) []string {
+	if ns == "" || strings.Contains(ns, "/") {
+		return nil
+	}
+	return readResolvConf(filepath.Join("/etc/netns", ns, "resolv.conf"), "")
+}
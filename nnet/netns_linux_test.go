@@ -0,0 +1,133 @@
+//go:build linux
+
+package nnet
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// newTestNetns 创建一个只有 lo 的网络命名空间，返回其路径
+// 命名空间由一个锁定的线程持有，测试结束时随线程退出而销毁，没有 CAP_SYS_ADMIN 时跳过测试
+func newTestNetns(t *testing.T) string {
+	t.Helper()
+	paths := make(chan string)
+	errs := make(chan error)
+	done := make(chan struct{})
+	go func() {
+		// 不解锁，线程随 goroutine 结束而退出
+		runtime.LockOSThread()
+		if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+			errs <- err
+			return
+		}
+		lo, err := netlink.LinkByName("lo")
+		if err == nil {
+			err = netlink.LinkSetUp(lo)
+		}
+		if err != nil {
+			errs <- err
+			return
+		}
+		paths <- fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), unix.Gettid())
+		<-done
+	}()
+	select {
+	case path := <-paths:
+		t.Cleanup(func() { close(done) })
+		return path
+	case err := <-errs:
+		t.Skipf("cannot create network namespace (requires CAP_SYS_ADMIN): %v", err)
+		return ""
+	}
+}
+
+func TestRunInNetns(t *testing.T) {
+	ns := newTestNetns(t)
+
+	before, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inside []net.Interface
+	err = RunInNetns(ns, func() error {
+		var err error
+		inside, err = net.Interfaces()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inside) != 1 || inside[0].Name != "lo" {
+		t.Errorf("interfaces in netns = %v, want only lo", inside)
+	}
+
+	// 执行后回到原命名空间
+	after, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("interfaces after RunInNetns = %v, want %v", after, before)
+	}
+}
+
+func TestHttpClientBindIPNetns(t *testing.T) {
+	ns := newTestNetns(t)
+
+	// 服务器只监听在新命名空间的 lo 上
+	var ln net.Listener
+	err := RunInNetns(ns, func() error {
+		var err error
+		ln, err = net.Listen("tcp4", "127.0.0.1:0")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "netns ", r.RemoteAddr)
+	})}
+	go server.Serve(ln)
+	defer server.Close()
+
+	const localIP = "127.0.0.1"
+	bind := NewBinding(localIP, BindOptions{Netns: ns})
+	defer bind.Close()
+
+	client, err := NewHttpClientBindIP(bind, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("request in netns failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	host, _, _ := net.SplitHostPort(string(body[len("netns "):]))
+	if host != localIP {
+		t.Errorf("server saw %s, want a connection from %s", body, localIP)
+	}
+
+	// 不设置命名空间时在当前命名空间中连接，该端口上没有服务器
+	direct := NewBinding(localIP, BindOptions{})
+	defer direct.Close()
+	client, err = NewHttpClientBindIP(direct, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := client.Get("http://" + ln.Addr().String() + "/"); err == nil {
+		resp.Body.Close()
+		t.Errorf("request outside netns reached %s", ln.Addr())
+	}
+}
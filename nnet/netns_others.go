@@ -0,0 +1,18 @@
+//go:build !linux
+
+package nnet
+
+import "fmt"
+
+// RunInNetns 非 Linux 平台不支持网络命名空间，ns 为空时直接执行 fn
+func RunInNetns(ns string, fn func() error) error {
+	if ns == "" {
+		return fn()
+	}
+	return fmt.Errorf("network namespaces are only supported on linux")
+}
+
+// GetNetnsDNS 非 Linux 平台不支持网络命名空间
+func GetNetnsDNS(ns string) []string {
+	return nil
+}
@@ -10,13 +10,19 @@ import (
 	"time"
 )
 
-// NewHttpClientBindIP 根据IP地址绑定本地HTTP客户端
-func NewHttpClientBindIP(localIP string, timeout time.Duration) (*http.Client, error) {
+// newTransportBindIP 创建从本地IP发出连接的 Transport
+func newTransportBindIP(localIP string, opts BindOptions, timeout time.Duration) (*http.Transport, error) {
 	ip := net.ParseIP(localIP)
 	if ip == nil {
 		return nil, fmt.Errorf("invalid local IP: %s", localIP)
 	}
 
+	// DNS 查询同样从该IP发出
+	resolver, err := NewResolverBindIP(localIP, opts, timeout)
+	if err != nil {
+		return nil, err
+	}
+
 	// 解析本地IP
 	localAddr := &net.TCPAddr{
 		IP: ip,
@@ -28,18 +34,16 @@ func NewHttpClientBindIP(localIP string, timeout time.Duration) (*http.Client, e
 			dialer := &net.Dialer{
 				LocalAddr: localAddr,
 				Timeout:   timeout,
+				Resolver:  resolver,
 			}
 			return dialer.DialContext(ctx, network, addr)
 		},
+		TLSClientConfig: opts.TLS,
+		IdleConnTimeout: transportIdleTimeout,
 	}
-
-	client := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-		Transport: transport,
-		Timeout:   timeout,
+	if opts.Proxy != nil {
+		transport.Proxy = http.ProxyURL(opts.Proxy)
 	}
 
-	return client, nil
+	return transport, nil
 }
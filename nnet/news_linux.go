@@ -11,17 +11,25 @@ import (
 	"time"
 )
 
-// NewHttpClientBindIP 根据IP地址绑定本地HTTP客户端
-func NewHttpClientBindIP(localIP string, timeout time.Duration) (*http.Client, error) {
+// newTransportBindIP 创建从本地IP发出连接的 Transport
+// 选项中设置了网络命名空间时，连接与DNS查询均在该命名空间内建立
+func newTransportBindIP(localIP string, opts BindOptions, timeout time.Duration) (*http.Transport, error) {
 	ip := net.ParseIP(localIP)
 	if ip == nil {
 		return nil, fmt.Errorf("invalid local IP: %s", localIP)
 	}
 
+	// DNS 查询同样从该IP发出
+	resolver, err := NewResolverBindIP(localIP, opts, timeout)
+	if err != nil {
+		return nil, err
+	}
+
 	transport := &http.Transport{
 		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
 			var d net.Dialer
 			d.Timeout = timeout
+			d.Resolver = resolver
 
 			d.Control = func(network, address string, c syscall.RawConn) error {
 				var controlErr error
@@ -38,17 +46,25 @@ func NewHttpClientBindIP(localIP string, timeout time.Duration) (*http.Client, e
 				return controlErr
 			}
 
-			return d.DialContext(ctx, network, addr)
-		},
-	}
+			if opts.Netns == "" {
+				return d.DialContext(ctx, network, addr)
+			}
 
-	client := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
+			// 套接字在创建时归属当前线程的命名空间，仅使用 IPv4 避免拨号分散到其他线程
+			var conn net.Conn
+			err := RunInNetns(opts.Netns, func() error {
+				var err error
+				conn, err = d.DialContext(ctx, "tcp4", addr)
+				return err
+			})
+			return conn, err
 		},
-		Transport: transport,
-		Timeout:   timeout,
+		TLSClientConfig: opts.TLS,
+		IdleConnTimeout: transportIdleTimeout,
+	}
+	if opts.Proxy != nil {
+		transport.Proxy = http.ProxyURL(opts.Proxy)
 	}
 
-	return client, nil
+	return transport, nil
 }
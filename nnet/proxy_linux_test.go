@@ -0,0 +1,246 @@
+//go:build linux
+
+package nnet
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 测试时绑定的本地地址，Linux 上 127.0.0.0/8 均在 lo 上
+const proxyTestIP = "127.0.0.2"
+
+// testProxy 记录连接来源与请求目标的代理
+type testProxy struct {
+	ln      net.Listener
+	lock    sync.Mutex
+	clients []string
+	targets []string
+}
+
+func (p *testProxy) record(client net.Addr, target string) {
+	host, _, _ := net.SplitHostPort(client.String())
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.clients = append(p.clients, host)
+	p.targets = append(p.targets, target)
+}
+
+func (p *testProxy) check(t *testing.T, target string) {
+	t.Helper()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if len(p.clients) == 0 {
+		t.Fatal("request did not go through the proxy")
+	}
+	for i := range p.clients {
+		if p.clients[i] != proxyTestIP {
+			t.Errorf("proxy connection from %s, want %s", p.clients[i], proxyTestIP)
+		}
+		if p.targets[i] != target {
+			t.Errorf("proxy target %s, want %s", p.targets[i], target)
+		}
+	}
+}
+
+// serve 接受连接，handshake 返回目标地址后双向转发
+func (p *testProxy) serve(handshake func(conn net.Conn, r *bufio.Reader) (string, func() error)) {
+	for {
+		conn, err := p.ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			target, reply := handshake(conn, r)
+			if target == "" {
+				return
+			}
+			p.record(conn.RemoteAddr(), target)
+			upstream, err := net.Dial("tcp", target)
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+			if reply() != nil {
+				return
+			}
+			go io.Copy(upstream, r)
+			io.Copy(conn, upstream)
+		}()
+	}
+}
+
+func newTestProxy(t *testing.T) *testProxy {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return &testProxy{ln: ln}
+}
+
+// newConnectProxy HTTP CONNECT 代理
+func newConnectProxy(t *testing.T) *testProxy {
+	p := newTestProxy(t)
+	go p.serve(func(conn net.Conn, r *bufio.Reader) (string, func() error) {
+		req, err := http.ReadRequest(r)
+		if err != nil || req.Method != "CONNECT" {
+			return "", nil
+		}
+		return req.Host, func() error {
+			_, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+			return err
+		}
+	})
+	return p
+}
+
+// newSocks5Proxy 不需认证的 SOCKS5 代理，只支持 CONNECT
+func newSocks5Proxy(t *testing.T) *testProxy {
+	p := newTestProxy(t)
+	go p.serve(func(conn net.Conn, r *bufio.Reader) (string, func() error) {
+		head := make([]byte, 2)
+		if _, err := io.ReadFull(r, head); err != nil || head[0] != 5 {
+			return "", nil
+		}
+		if _, err := io.ReadFull(r, make([]byte, head[1])); err != nil {
+			return "", nil
+		}
+		conn.Write([]byte{5, 0})
+
+		req := make([]byte, 4)
+		if _, err := io.ReadFull(r, req); err != nil || req[1] != 1 {
+			return "", nil
+		}
+		var host string
+		switch req[3] {
+		case 1:
+			ip := make([]byte, 4)
+			io.ReadFull(r, ip)
+			host = net.IP(ip).String()
+		case 3:
+			n, _ := r.ReadByte()
+			name := make([]byte, n)
+			io.ReadFull(r, name)
+			host = string(name)
+		default:
+			return "", nil
+		}
+		port := make([]byte, 2)
+		if _, err := io.ReadFull(r, port); err != nil {
+			return "", nil
+		}
+		target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+		return target, func() error {
+			_, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+			return err
+		}
+	})
+	return p
+}
+
+func proxyTestGet(t *testing.T, opts BindOptions, link string) string {
+	t.Helper()
+	bind := NewBinding(proxyTestIP, opts)
+	t.Cleanup(bind.Close)
+	return bindingGet(t, bind, link)
+}
+
+// bindingGet 通过绑定发送 GET 请求，返回响应体
+func bindingGet(t *testing.T, bind *Binding, link string) string {
+	t.Helper()
+	client, err := NewHttpClientBindIP(bind, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestHttpClientBindIPConnectProxy(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "portal")
+	}))
+	defer server.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	proxy := newConnectProxy(t)
+	body := proxyTestGet(t, BindOptions{
+		TLS:   &tls.Config{RootCAs: pool},
+		Proxy: &url.URL{Scheme: "http", Host: proxy.ln.Addr().String()},
+	}, server.URL)
+	if body != "portal" {
+		t.Errorf("body = %q", body)
+	}
+	proxy.check(t, server.Listener.Addr().String())
+}
+
+func TestHttpClientBindIPSocks5Proxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 目标只看到代理的地址
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		io.WriteString(w, host)
+	}))
+	defer server.Close()
+
+	proxy := newSocks5Proxy(t)
+	body := proxyTestGet(t, BindOptions{
+		Proxy: &url.URL{Scheme: "socks5", Host: proxy.ln.Addr().String()},
+	}, server.URL)
+	if body != "127.0.0.1" {
+		t.Errorf("server saw %q, want the proxy address", body)
+	}
+	proxy.check(t, server.Listener.Addr().String())
+}
+
+// 使用同一IP的实例只有设置了代理的经过代理
+func TestHttpClientBindIPProxyPerInstance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		io.WriteString(w, host)
+	}))
+	defer server.Close()
+
+	proxy := newSocks5Proxy(t)
+	proxied := NewBinding(proxyTestIP, BindOptions{
+		Proxy: &url.URL{Scheme: "socks5", Host: proxy.ln.Addr().String()},
+	})
+	defer proxied.Close()
+	direct := NewBinding(proxyTestIP, BindOptions{})
+	defer direct.Close()
+
+	if body := bindingGet(t, direct, server.URL); body != proxyTestIP {
+		t.Errorf("direct instance: server saw %q, want %s", body, proxyTestIP)
+	}
+	if body := bindingGet(t, proxied, server.URL); body != "127.0.0.1" {
+		t.Errorf("proxied instance: server saw %q, want the proxy address", body)
+	}
+	if body := bindingGet(t, direct, server.URL); body != proxyTestIP {
+		t.Errorf("direct instance after the proxied one: server saw %q, want %s", body, proxyTestIP)
+	}
+	proxy.check(t, server.Listener.Addr().String())
+	proxy.lock.Lock()
+	defer proxy.lock.Unlock()
+	if len(proxy.clients) != 1 {
+		t.Errorf("proxy saw %d connections, want 1", len(proxy.clients))
+	}
+}
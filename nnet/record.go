@@ -0,0 +1,482 @@
+package nnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HARLog HAR 格式的请求与响应记录，字段参照 HAR 1.2，自定义字段以 _ 开头
+type HARLog struct {
+	Log struct {
+		Version string `json:"version"`
+		Creator struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"creator"`
+		Entries []HAREntry `json:"entries"`
+	} `json:"log"`
+}
+
+// HAREntry 一次请求与响应
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	LocalIP         string      `json:"_localIP"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Headers     []HARHeader  `json:"headers"`
+	PostData    *HARPostData `json:"postData,omitempty"`
+}
+
+type HARResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []HARHeader `json:"headers"`
+	Content     HARContent  `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+}
+
+type HARHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// RecordedInstance 录制时实例使用的地址，回放时代替接口
+type RecordedInstance struct {
+	Interface string `json:"interface"`
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`
+}
+
+const (
+	redacted = "REDACTED"
+	// 录制时保存的响应体上限
+	recordBodyLimit = 1 << 20
+	// 实例地址的记录文件
+	recordInstancesFile = "instances.json"
+)
+
+// 按名称隐藏值的参数与请求头
+// srun 的 info 是用同一记录中的 challenge 加密的密码，Cookie 中含门户会话，均须隐藏
+var (
+	secretParamRe  = regexp.MustCompile(`(?i)^(password|passwd|pwd|pass|upass|user_?password|userpwd|token|info|chksum)$`)
+	secretHeaderRe = regexp.MustCompile(`(?i)^(authorization|proxy-authorization|cookie|set-cookie)$`)
+
+	recordFileNameRe = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+)
+
+var (
+	recordLock sync.Mutex
+	// 录制目录，为空时不录制
+	recordDir     string
+	recordVersion string
+	recordSeq     int
+	// 录制时需要隐藏的值，如各实例的密码
+	recordSecrets []string
+	recordHosts   map[string]RecordedInstance
+
+	// 回放的记录，为 nil 时不回放
+	replayEntries []*HAREntry
+	replayUsed    []bool
+	replayHosts   map[string]RecordedInstance
+)
+
+// StartRecording 开始将之后所有 HTTP 请求与响应写入 dir，每次请求一个 .har 文件
+// version 为记录中的程序版本
+func StartRecording(dir string, version string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	recordLock.Lock()
+	defer recordLock.Unlock()
+	recordDir = dir
+	recordVersion = version
+	recordSeq = 0
+	recordHosts = map[string]RecordedInstance{}
+	return nil
+}
+
+// AddSecret 录制时将 s 替换为 REDACTED
+func AddSecret(s string) {
+	if s == "" {
+		return
+	}
+	recordLock.Lock()
+	defer recordLock.Unlock()
+	for _, v := range recordSecrets {
+		if v == s {
+			return
+		}
+	}
+	recordSecrets = append(recordSecrets, s)
+}
+
+// RecordInstance 录制时保存实例使用的地址
+func RecordInstance(key string, inst RecordedInstance) {
+	recordLock.Lock()
+	defer recordLock.Unlock()
+	if recordDir == "" {
+		return
+	}
+	recordHosts[key] = inst
+	data, err := json.MarshalIndent(recordHosts, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(recordDir, recordInstancesFile), data, 0600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to record instance %s: %v\n", key, err)
+	}
+}
+
+// StartReplay 读取 dir 中的记录，之后的 HTTP 请求均由记录回应，不再访问网络
+func StartReplay(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.har"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	var entries []*HAREntry
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var har HARLog
+		if err := json.Unmarshal(data, &har); err != nil {
+			return fmt.Errorf("invalid capture %s: %v", file, err)
+		}
+		for i := range har.Log.Entries {
+			entries = append(entries, &har.Log.Entries[i])
+		}
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no .har files in %s", dir)
+	}
+
+	hosts := map[string]RecordedInstance{}
+	data, err := os.ReadFile(filepath.Join(dir, recordInstancesFile))
+	if err == nil {
+		err = json.Unmarshal(data, &hosts)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("invalid %s: %v", recordInstancesFile, err)
+	}
+
+	recordLock.Lock()
+	defer recordLock.Unlock()
+	replayEntries = entries
+	replayUsed = make([]bool, len(entries))
+	replayHosts = hosts
+	return nil
+}
+
+// Replaying 是否在回放记录
+func Replaying() bool {
+	recordLock.Lock()
+	defer recordLock.Unlock()
+	return replayEntries != nil
+}
+
+// ReplayInstance 返回录制时实例使用的地址
+func ReplayInstance(key string) (RecordedInstance, bool) {
+	recordLock.Lock()
+	defer recordLock.Unlock()
+	inst, ok := replayHosts[key]
+	return inst, ok
+}
+
+// replayClient 回放时返回由记录回应的客户端
+func replayClient(localIP string, timeout time.Duration) *http.Client {
+	if !Replaying() {
+		return nil
+	}
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: &replayTransport{localIP: localIP},
+		Timeout:   timeout,
+	}
+}
+
+// wrapTransport 录制时包装 Transport
+func wrapTransport(localIP string, rt http.RoundTripper) http.RoundTripper {
+	recordLock.Lock()
+	defer recordLock.Unlock()
+	if recordDir == "" {
+		return rt
+	}
+	return &recordTransport{localIP: localIP, next: rt}
+}
+
+type recordTransport struct {
+	localIP string
+	next    http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := HAREntry{
+		StartedDateTime: time.Now(),
+		LocalIP:         t.localIP,
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(req.Header),
+		},
+	}
+
+	// 读取请求体的副本
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			entry.Request.PostData = &HARPostData{MimeType: req.Header.Get("Content-Type"), Text: string(data)}
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	entry.Time = float64(time.Since(entry.StartedDateTime).Microseconds()) / 1000
+	if err != nil {
+		entry.Error = err.Error()
+		writeRecord(&entry)
+		return nil, err
+	}
+
+	// 只记录响应体的前 recordBodyLimit 字节，调用方仍读到完整的响应体
+	data, err := io.ReadAll(io.LimitReader(resp.Body, recordBodyLimit))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	entry.Response = HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Headers:     harHeaders(resp.Header),
+		Content:     HARContent{Size: len(data), MimeType: resp.Header.Get("Content-Type"), Text: string(data)},
+		RedirectURL: resp.Header.Get("Location"),
+	}
+	writeRecord(&entry)
+	return resp, nil
+}
+
+func harHeaders(h http.Header) []HARHeader {
+	var headers []HARHeader
+	for name, values := range h {
+		for _, v := range values {
+			headers = append(headers, HARHeader{Name: name, Value: v})
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+// writeRecord 隐藏敏感值后写入单独的 .har 文件
+func writeRecord(entry *HAREntry) {
+	recordLock.Lock()
+	defer recordLock.Unlock()
+	if recordDir == "" {
+		return
+	}
+
+	redactEntry(entry, recordSecrets)
+
+	var har HARLog
+	har.Log.Version = "1.2"
+	har.Log.Creator.Name = "gzgspd"
+	har.Log.Creator.Version = recordVersion
+	har.Log.Entries = []HAREntry{*entry}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(har); err != nil {
+		return
+	}
+
+	recordSeq++
+	host := recordFileNameRe.ReplaceAllString(hostOf(entry.Request.URL), "_")
+	file := filepath.Join(recordDir, fmt.Sprintf("%04d-%s-%s.har", recordSeq, entry.Request.Method, host))
+	if err := os.WriteFile(file, buf.Bytes(), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to record %s: %v\n", entry.Request.URL, err)
+	}
+}
+
+func hostOf(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// redactEntry 隐藏密码类参数、认证头以及 secrets 中的值
+func redactEntry(entry *HAREntry, secrets []string) {
+	replacer := secretReplacer(secrets)
+
+	if u, err := url.Parse(entry.Request.URL); err == nil {
+		u.RawQuery = redactQuery(u.RawQuery)
+		entry.Request.URL = u.String()
+	}
+	entry.Request.URL = replacer.Replace(entry.Request.URL)
+	entry.Response.RedirectURL = replacer.Replace(entry.Response.RedirectURL)
+
+	for _, headers := range [][]HARHeader{entry.Request.Headers, entry.Response.Headers} {
+		for i := range headers {
+			if secretHeaderRe.MatchString(headers[i].Name) {
+				headers[i].Value = redacted
+			}
+			headers[i].Value = replacer.Replace(headers[i].Value)
+		}
+	}
+
+	if pd := entry.Request.PostData; pd != nil {
+		if strings.HasPrefix(pd.MimeType, "application/x-www-form-urlencoded") {
+			pd.Text = redactQuery(pd.Text)
+		}
+		pd.Text = replacer.Replace(pd.Text)
+	}
+	entry.Response.Content.Text = replacer.Replace(entry.Response.Content.Text)
+}
+
+// redactQuery 隐藏查询串或表单中的密码类参数，保持参数顺序
+func redactQuery(query string) string {
+	if query == "" {
+		return query
+	}
+	parts := strings.Split(query, "&")
+	for i, part := range parts {
+		name, _, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		if key, err := url.QueryUnescape(name); err == nil && secretParamRe.MatchString(key) {
+			parts[i] = name + "=" + redacted
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// secretReplacer 替换原值与其 URL 编码形式
+func secretReplacer(secrets []string) *strings.Replacer {
+	var pairs []string
+	for _, s := range secrets {
+		pairs = append(pairs, s, redacted)
+		if e := url.QueryEscape(s); e != s {
+			pairs = append(pairs, e, redacted)
+		}
+		if e := url.PathEscape(s); e != s {
+			pairs = append(pairs, e, redacted)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// replayTransport 按记录回应请求
+// 依次使用同一本地地址、方法与地址（不含查询串）的首个未用记录，查询串完全相同的记录优先
+type replayTransport struct {
+	localIP string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	entry := takeReplayEntry(t.localIP, req)
+	if entry == nil {
+		return nil, fmt.Errorf("replay: no recorded response for %s %s", req.Method, req.URL)
+	}
+	if entry.Error != "" {
+		return nil, fmt.Errorf("replay: %s", entry.Error)
+	}
+
+	header := http.Header{}
+	for _, h := range entry.Response.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Response.Status, entry.Response.StatusText),
+		StatusCode:    entry.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(entry.Response.Content.Text)),
+		ContentLength: int64(len(entry.Response.Content.Text)),
+		Request:       req,
+	}, nil
+}
+
+func takeReplayEntry(localIP string, req *http.Request) *HAREntry {
+	recordLock.Lock()
+	defer recordLock.Unlock()
+
+	base := func(u *url.URL) string {
+		c := *u
+		c.RawQuery = ""
+		c.Fragment = ""
+		return c.String()
+	}
+	want := base(req.URL)
+
+	found := -1
+	for i, e := range replayEntries {
+		if replayUsed[i] || e.Request.Method != req.Method {
+			continue
+		}
+		if localIP != "" && e.LocalIP != "" && e.LocalIP != localIP {
+			continue
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || base(u) != want {
+			continue
+		}
+		if u.RawQuery == req.URL.RawQuery {
+			found = i
+			break
+		}
+		if found < 0 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return nil
+	}
+	replayUsed[found] = true
+	return replayEntries[found]
+}
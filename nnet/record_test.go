@@ -0,0 +1,99 @@
+package nnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactEntry(t *testing.T) {
+	entry := HAREntry{
+		Request: HARRequest{
+			URL: "http://10.0.0.1/cgi-bin/srun_portal?action=login&username=u&password=%7BMD5%7Dabc&info=%7BSRBX1%7Dxyz&chksum=123&ac_id=1",
+			Headers: []HARHeader{
+				{Name: "Cookie", Value: "JSESSIONID=abc"},
+				{Name: "User-Agent", Value: "ua"},
+			},
+			PostData: &HARPostData{MimeType: "application/x-www-form-urlencoded", Text: "userId=u&pwd=secret&service=x"},
+		},
+		Response: HARResponse{
+			Headers: []HARHeader{{Name: "Set-Cookie", Value: "JSESSIONID=def; Path=/"}},
+			Content: HARContent{Text: `{"user":"u","pass":"hunter2"}`},
+		},
+	}
+	redactEntry(&entry, []string{"hunter2"})
+
+	wantURL := "http://10.0.0.1/cgi-bin/srun_portal?action=login&username=u&password=REDACTED&info=REDACTED&chksum=REDACTED&ac_id=1"
+	if entry.Request.URL != wantURL {
+		t.Errorf("URL = %s, want %s", entry.Request.URL, wantURL)
+	}
+	if got := entry.Request.Headers[0].Value; got != redacted {
+		t.Errorf("Cookie = %s", got)
+	}
+	if got := entry.Request.Headers[1].Value; got != "ua" {
+		t.Errorf("User-Agent = %s", got)
+	}
+	if got := entry.Response.Headers[0].Value; got != redacted {
+		t.Errorf("Set-Cookie = %s", got)
+	}
+	if got := entry.Request.PostData.Text; got != "userId=u&pwd=REDACTED&service=x" {
+		t.Errorf("PostData = %s", got)
+	}
+	if got := entry.Response.Content.Text; strings.Contains(got, "hunter2") {
+		t.Errorf("Content = %s", got)
+	}
+}
+
+func TestRecordTransportFullBody(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), recordBodyLimit+100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := StartRecording(dir, "test"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		recordLock.Lock()
+		recordDir = ""
+		recordLock.Unlock()
+	}()
+
+	client := &http.Client{Transport: wrapTransport("", http.DefaultTransport)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != len(body) {
+		t.Errorf("read %d bytes, want %d", len(data), len(body))
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.har"))
+	if len(files) != 1 {
+		t.Fatalf("recorded %d files, want 1", len(files))
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har HARLog
+	if err := json.Unmarshal(raw, &har); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(har.Log.Entries[0].Response.Content.Text); got != recordBodyLimit {
+		t.Errorf("recorded %d bytes, want %d", got, recordBodyLimit)
+	}
+}
@@ -0,0 +1,249 @@
+package nnet
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// NewResolverBindIP 创建从指定本地IP发出查询的DNS解析器
+// opts.DNS 为空时使用系统配置的DNS服务器，opts.Netns 不为空时在该命名空间内建立连接
+func NewResolverBindIP(localIP string, opts BindOptions, timeout time.Duration) (*net.Resolver, error) {
+	ip := net.ParseIP(localIP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid local IP: %s", localIP)
+	}
+
+	// 只保留与本地IP同协议族的服务器
+	var addrs []string
+	for _, s := range opts.DNS {
+		addr, err := dnsServerAddr(s)
+		if err != nil {
+			return nil, err
+		}
+		host, _, _ := net.SplitHostPort(addr)
+		if (net.ParseIP(host).To4() != nil) == (ip.To4() != nil) {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	var next uint32
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			// 轮流使用链路的DNS服务器替换系统DNS服务器
+			if len(addrs) > 0 {
+				address = addrs[int(atomic.AddUint32(&next, 1)-1)%len(addrs)]
+			}
+
+			d := net.Dialer{Timeout: timeout}
+			host, _, _ := net.SplitHostPort(address)
+			if (net.ParseIP(host).To4() != nil) == (ip.To4() != nil) {
+				if strings.HasPrefix(network, "udp") {
+					d.LocalAddr = &net.UDPAddr{IP: ip}
+				} else {
+					d.LocalAddr = &net.TCPAddr{IP: ip}
+				}
+			}
+
+			var conn net.Conn
+			err := RunInNetns(opts.Netns, func() error {
+				var err error
+				conn, err = d.DialContext(ctx, network, address)
+				return err
+			})
+			return conn, err
+		},
+	}, nil
+}
+
+// dnsServerAddr 将 "IP" 或 "IP:端口" 格式的DNS服务器转换为拨号地址
+func dnsServerAddr(server string) (string, error) {
+	if ip := net.ParseIP(server); ip != nil {
+		return net.JoinHostPort(ip.String(), "53"), nil
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid DNS server: %s", server)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// GetIfDNS 获取接口通过DHCP获得的DNS服务器
+// 依次读取 systemd-networkd/resolved、OpenWrt netifd、dhcpcd、resolvconf 与 dhclient 的记录
+func GetIfDNS(ifName string) ([]string, error) {
+	iface, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return nil, err
+	}
+	index := strconv.Itoa(iface.Index)
+
+	// systemd-networkd 租约与 systemd-resolved 链路状态
+	if s := readKeyValueServers("/run/systemd/netif/leases/"+index, "DNS"); len(s) > 0 {
+		return s, nil
+	}
+	if s := readKeyValueServers("/run/systemd/resolve/netif/"+index, "SERVERS"); len(s) > 0 {
+		return s, nil
+	}
+
+	// OpenWrt netifd 以逻辑接口（如 wan）而非设备（如 eth0.2）记录 DNS 服务器，
+	// 先由 ubus 找到使用该设备的逻辑接口，ubus 未给出服务器时读取按逻辑接口分段写入的 resolv.conf.auto
+	if out, err := ubusCall("network.interface", "dump"); err == nil {
+		names, servers := parseNetifdDump(out, ifName)
+		if len(servers) > 0 {
+			return servers, nil
+		}
+		for _, name := range names {
+			for _, path := range []string{"/tmp/resolv.conf.d/resolv.conf.auto", "/tmp/resolv.conf.auto"} {
+				if s := readResolvConf(path, name); len(s) > 0 {
+					return s, nil
+				}
+			}
+		}
+	}
+
+	// dhcpcd 与 resolvconf 的单接口文件
+	for _, path := range []string{
+		"/run/dhcpcd/resolv.conf/" + ifName + ".dhcp",
+		"/run/resolvconf/interface/" + ifName + ".dhcp",
+		"/run/resolvconf/interface/" + ifName + ".dhclient",
+	} {
+		if s := readResolvConf(path, ""); len(s) > 0 {
+			return s, nil
+		}
+	}
+
+	// dhclient 租约文件
+	for _, path := range []string{
+		"/var/lib/dhcp/dhclient." + ifName + ".leases",
+		"/var/lib/dhclient/dhclient-" + ifName + ".leases",
+	} {
+		if s := readDhclientLeases(path); len(s) > 0 {
+			return s, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no DNS servers found for interface %s", ifName)
+}
+
+// ubusCall 调用 OpenWrt ubus 方法，返回 JSON 输出
+func ubusCall(object string, method string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, "ubus", "call", object, method).Output()
+}
+
+// parseNetifdDump 从 network.interface dump 的结果中找出使用设备 ifName 的逻辑接口及其 DNS 服务器
+func parseNetifdDump(data []byte, ifName string) ([]string, []string) {
+	var dump struct {
+		Interface []struct {
+			Interface string   `json:"interface"`
+			Device    string   `json:"device"`
+			L3Device  string   `json:"l3_device"`
+			DNSServer []string `json:"dns-server"`
+		} `json:"interface"`
+	}
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, nil
+	}
+
+	var names, servers []string
+	for _, iface := range dump.Interface {
+		if iface.L3Device != ifName && iface.Device != ifName {
+			continue
+		}
+		names = append(names, iface.Interface)
+		for _, server := range iface.DNSServer {
+			if net.ParseIP(server) != nil {
+				servers = append(servers, server)
+			}
+		}
+	}
+	return names, servers
+}
+
+// readResolvConf 读取 resolv.conf 格式文件中的 nameserver
+// section 不为空时只读取 "# Interface <section>" 之后的条目
+func readResolvConf(path string, section string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var servers []string
+	inSection := section == ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if section != "" && len(fields) == 3 && fields[0] == "#" && fields[1] == "Interface" {
+			inSection = fields[2] == section
+			continue
+		}
+		if inSection && len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+			servers = append(servers, fields[1])
+		}
+	}
+	return servers
+}
+
+// readKeyValueServers 读取 systemd 状态文件中以空格分隔的服务器列表
+func readKeyValueServers(path string, key string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || k != key {
+			continue
+		}
+		for _, s := range strings.Fields(v) {
+			if _, err := dnsServerAddr(s); err == nil {
+				servers = append(servers, s)
+			}
+		}
+	}
+	return servers
+}
+
+// readDhclientLeases 读取 dhclient 租约文件中最后一个租约的 domain-name-servers
+func readDhclientLeases(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		v, ok := strings.CutPrefix(line, "option domain-name-servers ")
+		if !ok {
+			continue
+		}
+		servers = servers[:0]
+		for _, s := range strings.Split(strings.TrimSuffix(v, ";"), ",") {
+			if s = strings.TrimSpace(s); net.ParseIP(s) != nil {
+				servers = append(servers, s)
+			}
+		}
+	}
+	return servers
+}
@@ -0,0 +1,53 @@
+package nnet
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// netifdDump OpenWrt 上 ubus call network.interface dump 的输出（已删减）
+const netifdDump = `{
+	"interface": [
+		{"interface": "lan", "up": true, "device": "br-lan", "l3_device": "br-lan", "dns-server": []},
+		{"interface": "wan", "up": true, "device": "eth0.2", "l3_device": "eth0.2", "dns-server": ["10.20.16.1", "223.5.5.5"]},
+		{"interface": "wan6", "up": true, "device": "eth0.2", "l3_device": "eth0.2", "dns-server": []},
+		{"interface": "vpn", "up": true, "device": "tun0", "l3_device": "tun0"}
+	]
+}`
+
+func TestParseNetifdDump(t *testing.T) {
+	tests := []struct {
+		ifName  string
+		names   []string
+		servers []string
+	}{
+		{"eth0.2", []string{"wan", "wan6"}, []string{"10.20.16.1", "223.5.5.5"}},
+		{"tun0", []string{"vpn"}, nil},
+		{"eth1", nil, nil},
+	}
+	for _, tt := range tests {
+		names, servers := parseNetifdDump([]byte(netifdDump), tt.ifName)
+		if !slices.Equal(names, tt.names) || !slices.Equal(servers, tt.servers) {
+			t.Errorf("parseNetifdDump(%s) = %v, %v, want %v, %v", tt.ifName, names, servers, tt.names, tt.servers)
+		}
+	}
+}
+
+func TestReadResolvConfSection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf.auto")
+	data := "# Interface wan\nnameserver 10.20.16.1\nnameserver 223.5.5.5\n# Interface wan6\nnameserver 2001:db8::1\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readResolvConf(path, "wan"); !slices.Equal(got, []string{"10.20.16.1", "223.5.5.5"}) {
+		t.Errorf("readResolvConf(wan) = %v", got)
+	}
+	if got := readResolvConf(path, "eth0.2"); got != nil {
+		t.Errorf("readResolvConf(eth0.2) = %v, want none", got)
+	}
+	if got := readResolvConf(path, ""); len(got) != 3 {
+		t.Errorf("readResolvConf() = %v, want all servers", got)
+	}
+}
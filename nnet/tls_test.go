@@ -0,0 +1,92 @@
+package nnet
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTLSConfigPin(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	hash := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	pin := base64.StdEncoding.EncodeToString(hash[:])
+	other := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		name    string
+		opts    TLSOptions
+		wantErr string
+	}{
+		{"self-signed without options", TLSOptions{MinVersion: "1.2"}, "certificate"},
+		{"pinned self-signed", TLSOptions{PinSHA256: []string{pin}}, ""},
+		{"pinned with prefix", TLSOptions{PinSHA256: []string{"sha256/" + pin}}, ""},
+		{"pinned and skip verify", TLSOptions{PinSHA256: []string{pin}, InsecureSkipVerify: true}, ""},
+		{"wrong pin", TLSOptions{PinSHA256: []string{other}}, "does not match any pin (server key sha256/" + pin + ")"},
+		{"wrong pin and skip verify", TLSOptions{PinSHA256: []string{other}, InsecureSkipVerify: true}, "does not match any pin"},
+		{"skip verify", TLSOptions{InsecureSkipVerify: true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewTLSConfig(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Get() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Get() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 使用同一IP的实例各自使用自己的 TLS 设置
+func TestBindingTLSPerInstance(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	hash := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	pinned, err := NewTLSConfig(TLSOptions{PinSHA256: []string{base64.StdEncoding.EncodeToString(hash[:])}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const localIP = "127.0.0.1"
+	a := NewBinding(localIP, BindOptions{TLS: pinned})
+	defer a.Close()
+	// 后创建的实例不设置 TLS 选项，不影响已创建的实例
+	b := NewBinding(localIP, BindOptions{})
+	defer b.Close()
+
+	get := func(bind *Binding) error {
+		client, err := NewHttpClientBindIP(bind, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get(a); err != nil {
+		t.Errorf("pinned instance: %v", err)
+	}
+	if err := get(b); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("instance without pins: error = %v, want a certificate error", err)
+	}
+	if err := get(a); err != nil {
+		t.Errorf("pinned instance after the other one: %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package portal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/summonhim/gzgspd/nnet"
+)
+
+// DrcomResponse Dr.COM eportal 接口返回的结构
+// result 在不同版本中可能为数字或字符串
+type DrcomResponse struct {
+	Result  interface{} `json:"result"`
+	Msg     string      `json:"msg"`
+	RetCode interface{} `json:"ret_code"`
+	UID     string      `json:"uid"`
+	V46IP   string      `json:"v46ip"`
+}
+
+// OK result 为 1 时成功
+func (r *DrcomResponse) OK() bool {
+	return fmt.Sprint(r.Result) == "1"
+}
+
+// AlreadyOnline 登录返回 ret_code 为 2 时表示已在线
+func (r *DrcomResponse) AlreadyOnline() bool {
+	return fmt.Sprint(r.RetCode) == "2"
+}
+
+// DrcomClient 登录时提交的客户端信息，通常来自跳转链接
+type DrcomClient struct {
+	IP     string
+	MAC    string
+	AcIP   string
+	AcName string
+}
+
+const drcomJSVersion = "4.1.3"
+
+var (
+	// Dr.COM 跳转链接的特征，如 /a79.htm?wlanuserip=... 或 /eportal/...
+	drcomRedirectRe = regexp.MustCompile(`/a\d+\.htm|/eportal/|drcom`)
+	// 旧版页面中的在线信息，如 uid='2023000001';
+	drcomUIDRe = regexp.MustCompile(`uid='([^']*)'`)
+	// 旧版页面中的提示代码，如 Msg=01;
+	drcomMsgRe = regexp.MustCompile(`Msg=(\d+)`)
+)
+
+// DrcomPortalChecker 检查当前网络是否需要登录，返回检测经过的跳转与登录链接
+// host 不为空时只接受跳转到该主机的链接，否则接受带 Dr.COM 特征的链接，
+// 或指向私有地址根路径的链接（不同于 kAliveLink 的主机，避免 http 升级到 https 被当作门户）
+func DrcomPortalChecker(bind *nnet.Binding, jar http.CookieJar, kAliveLink string, host string) *PortalWalk {
+	start, _ := url.Parse(kAliveLink)
+	return WalkPortal(bind, jar, kAliveLink, func(link *url.URL) bool {
+		if link.Host == "" {
+			return false
+		}
+		if host != "" {
+			return link.Hostname() == host
+		}
+		if drcomRedirectRe.MatchString(link.String()) {
+			return true
+		}
+		// 旧版页面通常直接跳转到门户根路径
+		if link.Path != "" && link.Path != "/" {
+			return false
+		}
+		ip := net.ParseIP(link.Hostname())
+		return ip != nil && ip.IsPrivate() && (start == nil || start.Hostname() != link.Hostname())
+	})
+}
+
+// DrcomClientFromLink 从跳转链接中取得客户端信息，缺少的字段为空
+func DrcomClientFromLink(link *url.URL) DrcomClient {
+	q := link.Query()
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := q.Get(k); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	return DrcomClient{
+		IP:     first("wlanuserip", "wlan_user_ip", "UserIP"),
+		MAC:    first("wlanusermac", "wlan_user_mac", "usermac"),
+		AcIP:   first("wlanacip", "wlan_ac_ip"),
+		AcName: first("wlanacname", "wlan_ac_name"),
+	}
+}
+
+// eportal 接口地址，使用 baseURL 的主机与指定端口
+func drcomEportalURL(baseURL string, port int, path string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	return u.Scheme + "://" + net.JoinHostPort(u.Hostname(), strconv.Itoa(port)) + path, nil
+}
+
+// drcomJSONP 以 JSONP 方式调用 eportal 接口
+func drcomJSONP(bind *nnet.Binding, link string, user_agent string, params url.Values) (*DrcomResponse, error) {
+	params.Set("jsVersion", drcomJSVersion)
+	params.Set("v", strconv.Itoa(int(time.Now().UnixMilli()%10000)))
+	params.Set("lang", "zh")
+
+	req, err := http.NewRequest("GET", link+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", user_agent)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+
+	_, body, err := doRequest(bind, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result DrcomResponse
+	if err := json.Unmarshal(unwrapJSONP(body), &result); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %v", link, err)
+	}
+	return &result, nil
+}
+
+// DrcomEportalStatus 查询在线状态，result 为 1 时在线
+func DrcomEportalStatus(bind *nnet.Binding, baseURL string, user_agent string) (*DrcomResponse, error) {
+	params := url.Values{}
+	params.Set("callback", "dr1002")
+	return drcomJSONP(bind, baseURL+"/drcom/chkstatus", user_agent, params)
+}
+
+// DrcomEportalLogin 登录
+func DrcomEportalLogin(
+	bind *nnet.Binding,
+	baseURL string,
+	port int,
+	user_agent string,
+	username string,
+	password string,
+	client DrcomClient,
+) (*DrcomResponse, error) {
+	link, err := drcomEportalURL(baseURL, port, "/eportal/portal/login")
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("callback", "dr1003")
+	params.Set("login_method", "1")
+	params.Set("user_account", ",0,"+username)
+	params.Set("user_password", password)
+	params.Set("wlan_user_ip", client.IP)
+	params.Set("wlan_user_ipv6", "")
+	params.Set("wlan_user_mac", drcomMAC(client.MAC))
+	params.Set("wlan_ac_ip", client.AcIP)
+	params.Set("wlan_ac_name", client.AcName)
+	params.Set("terminal_type", "1")
+	return drcomJSONP(bind, link, user_agent, params)
+}
+
+// DrcomEportalLogout 登出
+func DrcomEportalLogout(bind *nnet.Binding, baseURL string, port int, user_agent string, client DrcomClient) (*DrcomResponse, error) {
+	link, err := drcomEportalURL(baseURL, port, "/eportal/portal/logout")
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("callback", "dr1004")
+	params.Set("login_method", "1")
+	params.Set("user_account", "drcom")
+	params.Set("user_password", "123")
+	params.Set("ac_logout", "1")
+	params.Set("register_mode", "1")
+	params.Set("wlan_user_ip", client.IP)
+	params.Set("wlan_user_ipv6", "")
+	params.Set("wlan_vlan_id", "1")
+	params.Set("wlan_user_mac", drcomMAC(client.MAC))
+	params.Set("wlan_ac_ip", client.AcIP)
+	params.Set("wlan_ac_name", client.AcName)
+	return drcomJSONP(bind, link, user_agent, params)
+}
+
+// eportal 使用不带分隔符的小写 MAC，未知时为全 0
+func drcomMAC(mac string) string {
+	mac = strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(mac))
+	if mac == "" {
+		return "000000000000"
+	}
+	return mac
+}
+
+// drcomPage 获取旧版页面
+func drcomPage(bind *nnet.Binding, req *http.Request, user_agent string) (string, error) {
+	req.Header.Set("User-Agent", user_agent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+
+	_, body, err := doRequest(bind, req)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// DrcomLegacyStatus 旧版页面的在线状态，在线时返回页面中的 uid
+func DrcomLegacyStatus(bind *nnet.Binding, baseURL string, user_agent string) (bool, string, error) {
+	req, err := http.NewRequest("GET", baseURL+"/", nil)
+	if err != nil {
+		return false, "", err
+	}
+	page, err := drcomPage(bind, req, user_agent)
+	if err != nil {
+		return false, "", err
+	}
+	if m := drcomUIDRe.FindStringSubmatch(page); m != nil && m[1] != "" {
+		return true, m[1], nil
+	}
+	return false, "", nil
+}
+
+// DrcomLegacyLogin 旧版登录，向 0.htm 提交表单
+// 返回页面中的提示代码，成功与否需要再查询在线状态确认
+func DrcomLegacyLogin(bind *nnet.Binding, baseURL string, user_agent string, username string, password string) (string, error) {
+	data := url.Values{}
+	data.Set("DDDDD", username)
+	data.Set("upass", password)
+	data.Set("R1", "0")
+	data.Set("R2", "")
+	data.Set("R3", "0")
+	data.Set("R6", "0")
+	data.Set("para", "00")
+	data.Set("0MKKey", "123456")
+
+	req, err := http.NewRequest("POST", baseURL+"/0.htm", strings.NewReader(data.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	page, err := drcomPage(bind, req, user_agent)
+	if err != nil {
+		return "", err
+	}
+	if m := drcomMsgRe.FindStringSubmatch(page); m != nil {
+		return m[1], nil
+	}
+	return "", nil
+}
+
+// DrcomLegacyLogout 旧版登出，访问 F.htm
+func DrcomLegacyLogout(bind *nnet.Binding, baseURL string, user_agent string) error {
+	req, err := http.NewRequest("GET", baseURL+"/F.htm", nil)
+	if err != nil {
+		return err
+	}
+	_, err = drcomPage(bind, req, user_agent)
+	return err
+}
+
+// 旧版页面提示代码的含义
+var drcomLegacyMessages = map[string]string{
+	"00": "unknown error",
+	"01": "wrong account or password",
+	"02": "account is in use elsewhere",
+	"03": "account can only be used at the bound address",
+	"04": "account balance or time is used up",
+	"05": "account is suspended",
+	"11": "account can only be used at the bound MAC",
+	"14": "logged out",
+	"15": "logged in",
+}
+
+// DrcomLegacyMessage 返回旧版提示代码的含义
+func DrcomLegacyMessage(code string) string {
+	if msg, ok := drcomLegacyMessages[code]; ok {
+		return fmt.Sprintf("%s (Msg=%s)", msg, code)
+	}
+	return fmt.Sprintf("Msg=%s", code)
+}
@@ -0,0 +1,224 @@
+package portal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestDrcomPortalChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/upgrade":
+			// 普通网站的 http 升级到 https，不是门户
+			http.Redirect(w, r, "https://"+r.Host+"/", http.StatusFound)
+		case "/portal":
+			http.Redirect(w, r, "/a79.htm?wlanuserip=10.20.33.7", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/upgrade", false},
+		{"/portal", true},
+		{"/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			walk := DrcomPortalChecker(testBinding(t), nil, server.URL+tt.path, "")
+			if walk.Found() != tt.want {
+				t.Errorf("DrcomPortalChecker() = %+v, want found %v", walk, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrcomClientFromLink(t *testing.T) {
+	link, _ := url.Parse("http://10.1.1.1/a79.htm?wlanuserip=10.20.33.7&wlanacip=10.1.1.2&wlanacname=NAS&wlanusermac=A4-5E-60-12-34-56")
+	got := DrcomClientFromLink(link)
+	want := DrcomClient{IP: "10.20.33.7", MAC: "A4-5E-60-12-34-56", AcIP: "10.1.1.2", AcName: "NAS"}
+	if got != want {
+		t.Errorf("DrcomClientFromLink() = %+v, want %+v", got, want)
+	}
+	if mac := drcomMAC(got.MAC); mac != "a45e60123456" {
+		t.Errorf("drcomMAC() = %s", mac)
+	}
+	if u, _ := drcomEportalURL("http://10.1.1.1/a79.htm", 801, "/eportal/portal/login"); u != "http://10.1.1.1:801/eportal/portal/login" {
+		t.Errorf("drcomEportalURL() = %s", u)
+	}
+}
+
+// newDrcomEportalServers 模拟 Dr.COM 门户页（chkstatus）与另一端口上的 eportal 接口
+// 返回门户页地址与 eportal 接口端口
+func newDrcomEportalServers(t *testing.T) (string, int) {
+	online := false
+	jsonp := func(w http.ResponseWriter, r *http.Request, v map[string]interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "%s(%s);", r.URL.Query().Get("callback"), data)
+	}
+
+	eportal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("jsVersion") == "" {
+			t.Errorf("%s without jsVersion", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/eportal/portal/login":
+			switch {
+			case q.Get("user_account") != ",0,2023001" || q.Get("user_password") != "secret":
+				jsonp(w, r, map[string]interface{}{"result": 0, "msg": "账号或密码错误", "ret_code": 1})
+			case q.Get("wlan_user_ip") != "10.20.33.7" || q.Get("wlan_user_mac") != "a45e60123456":
+				jsonp(w, r, map[string]interface{}{"result": 0, "msg": "client mismatch", "ret_code": 1})
+			case online:
+				jsonp(w, r, map[string]interface{}{"result": 0, "msg": "", "ret_code": 2})
+			default:
+				online = true
+				jsonp(w, r, map[string]interface{}{"result": 1, "msg": "Portal协议认证成功！"})
+			}
+		case "/eportal/portal/logout":
+			if q.Get("wlan_user_ip") != "10.20.33.7" {
+				jsonp(w, r, map[string]interface{}{"result": 0, "msg": "client mismatch"})
+				return
+			}
+			online = false
+			jsonp(w, r, map[string]interface{}{"result": "1", "msg": "Radius注销成功！"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(eportal.Close)
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/drcom/chkstatus" {
+			http.NotFound(w, r)
+			return
+		}
+		if online {
+			jsonp(w, r, map[string]interface{}{"result": 1, "uid": "2023001", "v46ip": "10.20.33.7"})
+		} else {
+			jsonp(w, r, map[string]interface{}{"result": 0, "msg": ""})
+		}
+	}))
+	t.Cleanup(page.Close)
+
+	_, port, _ := net.SplitHostPort(eportal.Listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return page.URL, n
+}
+
+func TestDrcomEportalFakeServer(t *testing.T) {
+	baseURL, port := newDrcomEportalServers(t)
+	bind, ua := testBinding(t), "test"
+	client := DrcomClient{IP: "10.20.33.7", MAC: "A4:5E:60:12:34:56"}
+
+	status, err := DrcomEportalStatus(bind, baseURL, ua)
+	if err != nil || status.OK() {
+		t.Fatalf("DrcomEportalStatus() = %+v, %v, want offline", status, err)
+	}
+
+	login, err := DrcomEportalLogin(bind, baseURL, port, ua, "2023001", "wrong", client)
+	if err != nil || login.OK() {
+		t.Fatalf("DrcomEportalLogin() with wrong password = %+v, %v", login, err)
+	}
+	login, err = DrcomEportalLogin(bind, baseURL, port, ua, "2023001", "secret", client)
+	if err != nil || !login.OK() {
+		t.Fatalf("DrcomEportalLogin() = %+v, %v", login, err)
+	}
+	login, err = DrcomEportalLogin(bind, baseURL, port, ua, "2023001", "secret", client)
+	if err != nil || !login.AlreadyOnline() {
+		t.Errorf("DrcomEportalLogin() when online = %+v, %v, want ret_code 2", login, err)
+	}
+
+	status, err = DrcomEportalStatus(bind, baseURL, ua)
+	if err != nil || !status.OK() || status.UID != "2023001" {
+		t.Fatalf("DrcomEportalStatus() = %+v, %v, want online", status, err)
+	}
+
+	// result 为字符串时同样识别
+	logout, err := DrcomEportalLogout(bind, baseURL, port, ua, client)
+	if err != nil || !logout.OK() {
+		t.Fatalf("DrcomEportalLogout() = %+v, %v", logout, err)
+	}
+	status, err = DrcomEportalStatus(bind, baseURL, ua)
+	if err != nil || status.OK() {
+		t.Errorf("DrcomEportalStatus() after logout = %+v, %v", status, err)
+	}
+}
+
+// newDrcomLegacyServer 模拟旧版 Dr.COM 页面：根路径显示在线 uid，0.htm 登录，F.htm 登出
+func newDrcomLegacyServer(t *testing.T) *httptest.Server {
+	online := false
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=gb2312")
+		switch r.URL.Path {
+		case "/":
+			if online {
+				fmt.Fprint(w, "<script>uid='2023001';flow='1024';fee='0';</script>")
+			} else {
+				fmt.Fprint(w, "<script>uid='';</script><form name='f1' action='0.htm'></form>")
+			}
+		case "/0.htm":
+			if r.Method != "POST" {
+				t.Errorf("0.htm requested with %s", r.Method)
+			}
+			r.ParseForm()
+			if r.PostForm.Get("DDDDD") != "2023001" || r.PostForm.Get("upass") != "secret" || r.PostForm.Get("0MKKey") == "" {
+				fmt.Fprint(w, "<script>Msg=01;msga='';</script>")
+				return
+			}
+			online = true
+			fmt.Fprint(w, "<script>Msg=15;</script>")
+		case "/F.htm":
+			online = false
+			fmt.Fprint(w, "<script>Msg=14;</script>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestDrcomLegacyFakeServer(t *testing.T) {
+	server := newDrcomLegacyServer(t)
+	defer server.Close()
+	bind, ua := testBinding(t), "test"
+
+	online, _, err := DrcomLegacyStatus(bind, server.URL, ua)
+	if err != nil || online {
+		t.Fatalf("DrcomLegacyStatus() = %v, %v, want offline", online, err)
+	}
+
+	code, err := DrcomLegacyLogin(bind, server.URL, ua, "2023001", "wrong")
+	if err != nil || code != "01" {
+		t.Fatalf("DrcomLegacyLogin() with wrong password = %q, %v", code, err)
+	}
+	if msg := DrcomLegacyMessage(code); msg != "wrong account or password (Msg=01)" {
+		t.Errorf("DrcomLegacyMessage() = %s", msg)
+	}
+	code, err = DrcomLegacyLogin(bind, server.URL, ua, "2023001", "secret")
+	if err != nil || code != "15" {
+		t.Fatalf("DrcomLegacyLogin() = %q, %v", code, err)
+	}
+
+	online, uid, err := DrcomLegacyStatus(bind, server.URL, ua)
+	if err != nil || !online || uid != "2023001" {
+		t.Fatalf("DrcomLegacyStatus() = %v, %q, %v, want online", online, uid, err)
+	}
+
+	if err := DrcomLegacyLogout(bind, server.URL, ua); err != nil {
+		t.Fatal(err)
+	}
+	online, _, err = DrcomLegacyStatus(bind, server.URL, ua)
+	if err != nil || online {
+		t.Errorf("DrcomLegacyStatus() after logout = %v, %v", online, err)
+	}
+}
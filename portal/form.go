@@ -0,0 +1,520 @@
+package portal
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/summonhim/gzgspd/nnet"
+)
+
+// FormField 表单字段或 Cookie，值为模板
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// FormRule 通用表单登录规则
+type FormRule struct {
+	URL          string      // 登录页，空时使用跳转链接
+	PortalMatch  string      // 跳转链接需匹配的正则，空时接受任意跳转
+	Form         string      // 表单选择：#id、序号或 name，空时为第一个带密码框的表单
+	Action       string      // 覆盖表单的提交地址
+	Method       string      // 覆盖表单的提交方法
+	Fields       []FormField // 填写或追加的字段
+	Cookies      []FormField // 请求登录页前设置的 Cookie
+	Success      string      // 登录结果需匹配的正则
+	SuccessJSON  string      // 登录结果为 JSON 时需存在的 JSONPath
+	SuccessValue string      // SuccessJSON 处应有的值，空时只需存在且不为 false、0 或空
+	LogoutURL    string      // 登出地址
+}
+
+// FormValues 模板中可使用的值
+type FormValues struct {
+	Username string
+	Password string
+	IP       string
+	MAC      string
+	URL      string     // 跳转链接或登录页
+	Query    url.Values // URL 的查询参数，如 {{.Query.Get "mac"}}
+}
+
+// FormResult 表单提交结果
+type FormResult struct {
+	URL     string // 最终地址
+	Status  int
+	Body    string
+	Checked bool // 规则中是否有成功条件
+	Success bool
+}
+
+// 表单登录最多跟随的跳转次数
+const formMaxHops = 5
+
+// 模板函数
+var formTemplateFuncs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"replace": strings.ReplaceAll,
+	"md5": func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+}
+
+// ExpandFormTemplate 使用 values 展开模板
+func ExpandFormTemplate(text string, values FormValues) (string, error) {
+	tmpl, err := template.New("").Funcs(formTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template '%s': %v", text, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", fmt.Errorf("template '%s': %v", text, err)
+	}
+	return buf.String(), nil
+}
+
+// FormPortalChecker 检查当前网络是否需要登录，返回检测经过的跳转与登录链接
+// match 不为空时跳转链接需匹配该正则
+func FormPortalChecker(bind *nnet.Binding, jar http.CookieJar, kAliveLink string, match string) *PortalWalk {
+	var re *regexp.Regexp
+	if match != "" {
+		var err error
+		if re, err = regexp.Compile(match); err != nil {
+			return &PortalWalk{}
+		}
+	}
+	return WalkPortal(bind, jar, kAliveLink, func(link *url.URL) bool {
+		return link.Host != "" && (re == nil || re.MatchString(link.String()))
+	})
+}
+
+// formClient 从 bind 发送请求并使用 jar 保存 Cookie 的客户端
+func formClient(bind *nnet.Binding, jar http.CookieJar) (*http.Client, error) {
+	client, err := nnet.NewHttpClientBindIP(bind, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	client.Jar = jar
+	return client, nil
+}
+
+// formFetch 发送请求并跟随 3xx 跳转，返回最终地址与响应体
+// 跳转后均使用 GET
+func formFetch(client *http.Client, req *http.Request, user_agent string) (*url.URL, *http.Response, []byte, error) {
+	for hop := 0; ; hop++ {
+		req.Header.Set("User-Agent", user_agent)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+			return req.URL, resp, body, nil
+		}
+		loc, err := resp.Location()
+		if err != nil {
+			return req.URL, resp, body, nil
+		}
+		if hop >= formMaxHops {
+			return nil, nil, nil, fmt.Errorf("too many redirects")
+		}
+		if req, err = http.NewRequest("GET", loc.String(), nil); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+}
+
+// FormLogin 按规则打开登录页、填写表单并提交
+func FormLogin(bind *nnet.Binding, jar http.CookieJar, user_agent string, rule *FormRule, values FormValues) (*FormResult, error) {
+	client, err := formClient(bind, jar)
+	if err != nil {
+		return nil, err
+	}
+
+	landing := values.URL
+	if rule.URL != "" {
+		if landing, err = ExpandFormTemplate(rule.URL, values); err != nil {
+			return nil, err
+		}
+	}
+	if landing == "" {
+		return nil, fmt.Errorf("no login page: set url or use a keep_alive_link that redirects")
+	}
+	landingURL, err := url.Parse(landing)
+	if err != nil {
+		return nil, fmt.Errorf("invalid login page '%s': %v", landing, err)
+	}
+	values.URL = landing
+	values.Query = landingURL.Query()
+
+	// 预置 Cookie
+	if jar != nil && len(rule.Cookies) > 0 {
+		var cookies []*http.Cookie
+		for _, c := range rule.Cookies {
+			v, err := ExpandFormTemplate(c.Value, values)
+			if err != nil {
+				return nil, err
+			}
+			cookies = append(cookies, &http.Cookie{Name: c.Name, Value: v, Path: "/"})
+		}
+		jar.SetCookies(landingURL, cookies)
+	}
+
+	// 打开登录页，页面没有表单时跟随其中的跳转
+	var pageURL *url.URL
+	var form *html.Node
+	link := landing
+	for hop := 0; ; hop++ {
+		req, err := http.NewRequest("GET", link, nil)
+		if err != nil {
+			return nil, err
+		}
+		var body []byte
+		pageURL, _, body, err = formFetch(client, req, user_agent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open login page: %v", err)
+		}
+		doc, err := html.Parse(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse login page: %v", err)
+		}
+		if form, err = selectForm(doc, rule.Form); err == nil {
+			break
+		}
+		redirects := pageRedirects(string(body))
+		if len(redirects) == 0 || hop >= formMaxHops {
+			return nil, fmt.Errorf("%s: %v", pageURL, err)
+		}
+		next, err := pageURL.Parse(redirects[0])
+		if err != nil {
+			return nil, err
+		}
+		link = next.String()
+	}
+
+	// 填写表单
+	data := formDefaults(form)
+	for _, f := range rule.Fields {
+		v, err := ExpandFormTemplate(f.Value, values)
+		if err != nil {
+			return nil, err
+		}
+		data.Set(f.Name, v)
+	}
+
+	action := attr(form, "action")
+	if rule.Action != "" {
+		if action, err = ExpandFormTemplate(rule.Action, values); err != nil {
+			return nil, err
+		}
+	}
+	actionURL, err := pageURL.Parse(action)
+	if err != nil {
+		return nil, fmt.Errorf("invalid form action '%s': %v", action, err)
+	}
+	method := strings.ToUpper(attr(form, "method"))
+	if rule.Method != "" {
+		method = strings.ToUpper(rule.Method)
+	}
+
+	// 提交
+	var req *http.Request
+	if method == "POST" {
+		req, err = http.NewRequest("POST", actionURL.String(), strings.NewReader(data.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		actionURL.RawQuery = data.Encode()
+		req, err = http.NewRequest("GET", actionURL.String(), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Referer", pageURL.String())
+	finalURL, resp, body, err := formFetch(client, req, user_agent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit form: %v", err)
+	}
+
+	result := &FormResult{URL: finalURL.String(), Status: resp.StatusCode, Body: string(body)}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if rule.Success != "" {
+		re, err := regexp.Compile(rule.Success)
+		if err != nil {
+			return result, fmt.Errorf("invalid success regex: %v", err)
+		}
+		result.Checked = true
+		result.Success = re.Match(body)
+	}
+	if rule.SuccessJSON != "" {
+		ok, err := jsonPathMatch(unwrapJSONP(body), rule.SuccessJSON, rule.SuccessValue)
+		if err != nil {
+			return result, err
+		}
+		result.Success = ok && (!result.Checked || result.Success)
+		result.Checked = true
+	}
+	return result, nil
+}
+
+// FormLogout 访问登出地址
+func FormLogout(bind *nnet.Binding, jar http.CookieJar, user_agent string, rule *FormRule, values FormValues) error {
+	if rule.LogoutURL == "" {
+		return fmt.Errorf("no logout_url in rule")
+	}
+	link, err := ExpandFormTemplate(rule.LogoutURL, values)
+	if err != nil {
+		return err
+	}
+	client, err := formClient(bind, jar)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return err
+	}
+	_, resp, _, err := formFetch(client, req, user_agent)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// attr 返回节点的属性值
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// findAll 按文档顺序返回所有 tag 节点
+func findAll(n *html.Node, tag string) []*html.Node {
+	var nodes []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == tag {
+			nodes = append(nodes, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return nodes
+}
+
+// textContent 返回节点内的文本
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// selectForm 按选择器找到表单
+// #id 匹配 id，数字为序号，其他匹配 name；为空时选择第一个带密码框的表单，没有则为第一个表单
+func selectForm(doc *html.Node, selector string) (*html.Node, error) {
+	forms := findAll(doc, "form")
+	if len(forms) == 0 {
+		return nil, fmt.Errorf("no form in login page")
+	}
+
+	switch {
+	case selector == "":
+		for _, f := range forms {
+			for _, in := range findAll(f, "input") {
+				if strings.EqualFold(attr(in, "type"), "password") {
+					return f, nil
+				}
+			}
+		}
+		return forms[0], nil
+	case strings.HasPrefix(selector, "#"):
+		for _, f := range forms {
+			if attr(f, "id") == selector[1:] {
+				return f, nil
+			}
+		}
+	default:
+		if i, err := strconv.Atoi(selector); err == nil {
+			if i >= 0 && i < len(forms) {
+				return forms[i], nil
+			}
+			break
+		}
+		for _, f := range forms {
+			if attr(f, "name") == selector {
+				return f, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no form matches '%s'", selector)
+}
+
+// formDefaults 按浏览器的方式收集表单中的默认值
+// 提交按钮不包含在内，需要时在规则中添加
+func formDefaults(form *html.Node) url.Values {
+	data := url.Values{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && hasAttr(n, "name") && !hasAttr(n, "disabled") {
+			name := attr(n, "name")
+			switch n.Data {
+			case "input":
+				switch strings.ToLower(attr(n, "type")) {
+				case "submit", "button", "image", "reset", "file":
+				case "checkbox", "radio":
+					if hasAttr(n, "checked") {
+						v := attr(n, "value")
+						if !hasAttr(n, "value") {
+							v = "on"
+						}
+						data.Add(name, v)
+					}
+				default:
+					data.Add(name, attr(n, "value"))
+				}
+			case "textarea":
+				data.Add(name, textContent(n))
+			case "select":
+				options := findAll(n, "option")
+				var chosen *html.Node
+				for _, o := range options {
+					if hasAttr(o, "selected") {
+						chosen = o
+						break
+					}
+				}
+				if chosen == nil && len(options) > 0 {
+					chosen = options[0]
+				}
+				if chosen != nil {
+					v := attr(chosen, "value")
+					if !hasAttr(chosen, "value") {
+						v = strings.TrimSpace(textContent(chosen))
+					}
+					data.Add(name, v)
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(form)
+	return data
+}
+
+var jsonPathTokenRe = regexp.MustCompile(`^(?:\.([A-Za-z0-9_$-]+)|\[(\d+)\]|\['([^']*)'\]|\["([^"]*)"\])`)
+
+// jsonPathLookup 按 JSONPath 取值，支持 $.a.b、$['a']、$.a[0] 形式
+func jsonPathLookup(doc interface{}, path string) (interface{}, bool, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(path), "$")
+	if !ok {
+		return nil, false, fmt.Errorf("invalid JSONPath '%s': must start with $", path)
+	}
+	node := doc
+	for rest != "" {
+		m := jsonPathTokenRe.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, false, fmt.Errorf("invalid JSONPath '%s' near '%s'", path, rest)
+		}
+		rest = rest[len(m[0]):]
+
+		if m[2] != "" {
+			i, _ := strconv.Atoi(m[2])
+			arr, ok := node.([]interface{})
+			if !ok || i >= len(arr) {
+				return nil, false, nil
+			}
+			node = arr[i]
+			continue
+		}
+		key := m[1] + m[3] + m[4]
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false, nil
+		}
+		if node, ok = obj[key]; !ok {
+			return nil, false, nil
+		}
+	}
+	return node, true, nil
+}
+
+// jsonPathMatch 检查 JSON 在 path 处的值
+// want 为空时值需存在且不为 null、false、0 或空字符串，否则按字符串比较
+func jsonPathMatch(body []byte, path string, want string) (bool, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return false, fmt.Errorf("login response is not JSON: %v", err)
+	}
+	v, found, err := jsonPathLookup(doc, path)
+	if err != nil || !found {
+		return false, err
+	}
+	if want != "" {
+		return fmt.Sprint(v) == want, nil
+	}
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	case string:
+		return v != "", nil
+	}
+	return true, nil
+}
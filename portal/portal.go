@@ -7,12 +7,9 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"regexp"
 	"strings"
 	"time"
 
-	"github.com/robertkrimen/otto"
-
 	"github.com/summonhim/gzgspd/nnet"
 )
 
@@ -117,272 +114,213 @@ type QuickAuthResponse struct {
 	OperatingBindCtrlList []interface{} `json:"operatingBindCtrlList"`
 }
 
-// TelecomPortalJsonAction 获取登录的基本信息
-func TelecomPortalJsonAction(
-	requestIP string,
-	scheme string,
-	host string,
-	user_agent string,
-	wlanuserip string,
-	wlanacname string,
-	mac string,
-	vlan string,
-	hostname string,
-	randStr string,
-) (*ActionResponse, error) {
-	// 构造 URL 参数
-	params := url.Values{}
-	params.Set("wlanuserip", wlanuserip)
-	params.Set("wlanacname", wlanacname)
-	params.Set("mac", mac)
-	params.Set("vlan", vlan)
-	params.Set("hostname", hostname)
-	params.Set("rand", randStr)
-	params.Set("viewStatus", "1")
+// TelecomClient 电信 ePortal 客户端，请求从绑定的本地IP发出
+type TelecomClient struct {
+	Scheme    string
+	Host      string
+	UserAgent string
+	HTTP      *http.Client
+}
 
-	fullURL := scheme + "://" + host + "/PortalJsonAction.do?" + params.Encode()
+// PortalInfoRequest PortalJsonAction 的参数，均取自跳转链接
+type PortalInfoRequest struct {
+	WlanUserIP string
+	WlanAcName string
+	MAC        string
+	Vlan       string
+	HostName   string
+	Rand       string
+}
 
-	// 创建请求
-	req, err := http.NewRequest("GET", fullURL, nil)
-	if err != nil {
-		return nil, err
+// LoginRequest quickauth.do 登录的参数
+type LoginRequest struct {
+	UserID       string
+	Password     string
+	WlanUserIP   string
+	WlanAcName   string
+	WlanAcIP     string
+	Vlan         string
+	MAC          string
+	Version      int
+	PortalPageID int
+	Timestamp    int64
+	UUID         string
+	PortalType   string
+	HostName     string
+	Rand         string
+}
+
+// LogoutRequest quickauthdisconn.do 登出的参数
+type LogoutRequest struct {
+	WlanAcIP      string
+	WlanUserIP    string
+	WlanAcName    string
+	Version       int
+	PortalType    string
+	UserID        string
+	MAC           string
+	GroupID       int
+	ClearOperator string
+}
+
+// TelecomError 门户返回非 2xx 状态或无法解析的响应，Body 为原始响应体
+type TelecomError struct {
+	Action string // 接口，如 quickauth.do
+	Status int
+	Body   []byte
+	Err    error // 解析响应的错误，状态码不为 2xx 时为空
+}
+
+// 错误信息中最多显示的响应体长度
+const telecomErrorBodySize = 200
+
+func (e *TelecomError) Error() string {
+	body := strings.TrimSpace(string(e.Body))
+	if len(body) > telecomErrorBodySize {
+		body = body[:telecomErrorBodySize] + "..."
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: invalid response (status %d): %v: %q", e.Action, e.Status, e.Err, body)
 	}
+	return fmt.Sprintf("%s: unexpected status %d %s: %q", e.Action, e.Status, http.StatusText(e.Status), body)
+}
 
-	// 设置请求头
-	req.Header.Set("User-Agent", user_agent)
-	req.Header.Set("Accept", "application/json, text/javascript, */*; q=0.01")
-	req.Header.Set("Accept-Language", "zh-CN")
+func (e *TelecomError) Unwrap() error {
+	return e.Err
+}
 
-	// 发起请求
-	client, err := nnet.NewHttpClientBindIP(requestIP, 5*time.Second)
+// NewTelecomClient 创建访问 scheme://host 门户的客户端
+func NewTelecomClient(bind *nnet.Binding, scheme string, host string, user_agent string) (*TelecomClient, error) {
+	client, err := nnet.NewHttpClientBindIP(bind, 5*time.Second)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := client.Do(req)
+	return &TelecomClient{
+		Scheme:    scheme,
+		Host:      host,
+		UserAgent: user_agent,
+		HTTP:      client,
+	}, nil
+}
+
+// do 发送请求并将 JSON 响应解析到 result
+func (c *TelecomClient) do(action string, req *http.Request, result interface{}) error {
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set("Accept", "application/json, text/javascript, */*; q=0.01")
+
+	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// 解析 JSON 到 struct
-	var result ActionResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &TelecomError{Action: action, Status: resp.StatusCode, Body: body}
 	}
-
-	return &result, nil
+	if err := json.Unmarshal(body, result); err != nil {
+		return &TelecomError{Action: action, Status: resp.StatusCode, Body: body, Err: err}
+	}
+	return nil
 }
 
-// TelecomQuickAuth 登录
-func TelecomQuickAuth(
-	requestIP string,
-	scheme string,
-	host string,
-	user_agent string,
-	userid string,
-	passwd string,
-	wlanuserip string,
-	wlanacname string,
-	wlanacIp string,
-	vlan string,
-	mac string,
-	version int,
-	portalpageid int,
-	timestamp int64,
-	uuid string,
-	portaltype string,
-	hostname string,
-	rand string,
-) (*QuickAuthResponse, error) {
+// PortalInfo 获取登录的基本信息
+func (c *TelecomClient) PortalInfo(r PortalInfoRequest) (*ActionResponse, error) {
 	// 构造 URL 参数
 	params := url.Values{}
-	params.Set("userid", userid)
-	params.Set("passwd", passwd)
-	params.Set("wlanuserip", wlanuserip)
-	params.Set("wlanacname", wlanacname)
-	params.Set("wlanacIp", wlanacIp)
-	params.Set("vlan", vlan)
-	params.Set("mac", mac)
-	params.Set("version", fmt.Sprintf("%d", version))
-	params.Set("portalpageid", fmt.Sprintf("%d", portalpageid))
-	params.Set("timestamp", fmt.Sprintf("%d", timestamp))
-	params.Set("uuid", uuid)
-	params.Set("portaltype", portaltype)
-	params.Set("hostname", hostname)
-	params.Set("rand", rand)
-
-	fullURL := scheme + "://" + host + "/quickauth.do?" + params.Encode()
+	params.Set("wlanuserip", r.WlanUserIP)
+	params.Set("wlanacname", r.WlanAcName)
+	params.Set("mac", r.MAC)
+	params.Set("vlan", r.Vlan)
+	params.Set("hostname", r.HostName)
+	params.Set("rand", r.Rand)
+	params.Set("viewStatus", "1")
 
-	// 创建请求
-	req, err := http.NewRequest("GET", fullURL, nil)
+	req, err := http.NewRequest("GET", c.Scheme+"://"+c.Host+"/PortalJsonAction.do?"+params.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Accept-Language", "zh-CN")
 
-	// 设置请求头
-	req.Header.Set("User-Agent", user_agent)
-	req.Header.Set("Accept", "application/json, text/javascript, */*; q=0.01")
-	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en-US;q=0.8,en;q=0.7,ja;q=0.6")
-
-	// 发起请求
-	client, err := nnet.NewHttpClientBindIP(requestIP, 5*time.Second)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := client.Do(req)
-	if err != nil {
+	var result ActionResponse
+	if err := c.do("PortalJsonAction.do", req, &result); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return &result, nil
+}
 
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
+// Login 登录
+func (c *TelecomClient) Login(r LoginRequest) (*QuickAuthResponse, error) {
+	// 构造 URL 参数
+	params := url.Values{}
+	params.Set("userid", r.UserID)
+	params.Set("passwd", r.Password)
+	params.Set("wlanuserip", r.WlanUserIP)
+	params.Set("wlanacname", r.WlanAcName)
+	params.Set("wlanacIp", r.WlanAcIP)
+	params.Set("vlan", r.Vlan)
+	params.Set("mac", r.MAC)
+	params.Set("version", fmt.Sprintf("%d", r.Version))
+	params.Set("portalpageid", fmt.Sprintf("%d", r.PortalPageID))
+	params.Set("timestamp", fmt.Sprintf("%d", r.Timestamp))
+	params.Set("uuid", r.UUID)
+	params.Set("portaltype", r.PortalType)
+	params.Set("hostname", r.HostName)
+	params.Set("rand", r.Rand)
+
+	req, err := http.NewRequest("GET", c.Scheme+"://"+c.Host+"/quickauth.do?"+params.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en-US;q=0.8,en;q=0.7,ja;q=0.6")
 
-	// 解析 JSON
 	var result QuickAuthResponse
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := c.do("quickauth.do", req, &result); err != nil {
 		return nil, err
 	}
-
 	return &result, nil
 }
 
-// TelecomQuickAuthDisconn 登出
-func TelecomQuickAuthDisconn(
-	requestIP string,
-	scheme string,
-	host string,
-	user_agent string,
-	wlanacip string,
-	wlanuserip string,
-	wlanacname string,
-	version int,
-	portaltype string,
-	userid string,
-	mac string,
-	groupId int,
-	clearOperator string,
-) (*QuickAuthResponse, error) {
+// Logout 登出
+func (c *TelecomClient) Logout(r LogoutRequest) (*QuickAuthResponse, error) {
 	// 构造表单数据
 	data := url.Values{}
-	data.Set("wlanacip", wlanacip)
-	data.Set("wlanuserip", wlanuserip)
-	data.Set("wlanacname", wlanacname)
-	data.Set("version", fmt.Sprintf("%d", version))
-	data.Set("portaltype", portaltype)
-	data.Set("userid", userid)
-	data.Set("mac", mac)
-	data.Set("groupId", fmt.Sprintf("%d", groupId))
-	data.Set("clearOperator", clearOperator)
-
-	// 创建 POST 请求
-	req, err := http.NewRequest("POST", scheme+"://"+host+"/quickauthdisconn.do", bytes.NewBufferString(data.Encode()))
+	data.Set("wlanacip", r.WlanAcIP)
+	data.Set("wlanuserip", r.WlanUserIP)
+	data.Set("wlanacname", r.WlanAcName)
+	data.Set("version", fmt.Sprintf("%d", r.Version))
+	data.Set("portaltype", r.PortalType)
+	data.Set("userid", r.UserID)
+	data.Set("mac", r.MAC)
+	data.Set("groupId", fmt.Sprintf("%d", r.GroupID))
+	data.Set("clearOperator", r.ClearOperator)
+
+	req, err := http.NewRequest("POST", c.Scheme+"://"+c.Host+"/quickauthdisconn.do", bytes.NewBufferString(data.Encode()))
 	if err != nil {
 		return nil, err
 	}
-
-	// 设置请求头
-	req.Header.Set("User-Agent", user_agent)
-	req.Header.Set("Accept", "application/json, text/javascript, */*; q=0.01")
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en-US;q=0.8,en;q=0.7,ja;q=0.6")
 
-	// 发起请求
-	client, err := nnet.NewHttpClientBindIP(requestIP, 5*time.Second)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	// 解析 JSON
 	var result QuickAuthResponse
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := c.do("quickauthdisconn.do", req, &result); err != nil {
 		return nil, err
 	}
-
 	return &result, nil
 }
 
-// TelecomPortalChecker 检查当前网络是否需要登录，若为是则返回登录链接
-func TelecomPortalChecker(requestIP string, kAliveLink string) (bool, string) {
-	client, err := nnet.NewHttpClientBindIP(requestIP, 5*time.Second)
-	if err != nil {
-		return false, ""
-	}
-
-	resp, err := client.Get(kAliveLink)
-	if err != nil {
-		return false, ""
-	}
-	defer resp.Body.Close()
-
-	// 定义 portal 关键字
-	portalKeywords := []string{"portalScript.do", "portal.do"}
-
-	// 1️⃣ 检测 3xx 重定向
-	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
-		loc, err := resp.Location()
-		if err == nil {
-			for _, kw := range portalKeywords {
-				if strings.Contains(loc.String(), kw) {
-					return true, loc.String()
-				}
+// TelecomPortalChecker 检查当前网络是否需要登录，返回检测经过的跳转与登录链接
+// portalKeywords 为登录链接中应包含的关键字，任一匹配即视为门户
+func TelecomPortalChecker(bind *nnet.Binding, jar http.CookieJar, kAliveLink string, portalKeywords []string) *PortalWalk {
+	return WalkPortal(bind, jar, kAliveLink, func(link *url.URL) bool {
+		for _, kw := range portalKeywords {
+			if strings.Contains(link.String(), kw) {
+				return true
 			}
 		}
-	}
-
-	// 2️⃣ 检测 200 页面 + MAGI 或 portal 页面
-	if resp.StatusCode == 200 {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return false, ""
-		}
-		html := string(body)
-
-		re := regexp.MustCompile(`<script[^>]*>([\s\S]*?)</script>`)
-		scripts := re.FindAllStringSubmatch(html, -1)
-		for _, s := range scripts {
-			js := s[1]
-
-			vm := otto.New()
-			var finalURL string
-			vm.Set("location", map[string]interface{}{
-				"replace": func(call otto.FunctionCall) otto.Value {
-					s, _ := call.Argument(0).ToString()
-					finalURL = s
-					return otto.Value{}
-				},
-			})
-
-			_, err := vm.Run(js)
-			if err == nil && finalURL != "" {
-				for _, kw := range portalKeywords {
-					if strings.Contains(finalURL, kw) {
-						return true, finalURL
-					}
-				}
-			}
-		}
-	}
-
-	return false, ""
+		return false
+	})
 }
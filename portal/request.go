@@ -0,0 +1,55 @@
+package portal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/summonhim/gzgspd/nnet"
+)
+
+var (
+	// 跳转页面中的脚本跳转链接，形如 <script>top.self.location.href='http://x/eportal/index.jsp?...'</script>
+	scriptRedirectRe = regexp.MustCompile(`location\.href\s*=\s*['"]([^'"]+)['"]`)
+	// 跳转页面中的 meta refresh，如 <meta http-equiv="refresh" content="0; url=http://10.0.0.1/">
+	metaRefreshRe = regexp.MustCompile(`(?i)<meta[^>]+http-equiv=["']?refresh["']?[^>]+url=([^"'>\s]+)`)
+	// 页面中的脚本
+	scriptRe = regexp.MustCompile(`<script[^>]*>([\s\S]*?)</script>`)
+)
+
+// doRequest 通过 bind 的客户端发送请求，返回响应与响应体
+// 状态码不为 2xx 时返回错误
+func doRequest(bind *nnet.Binding, req *http.Request) (*http.Response, []byte, error) {
+	client, err := nnet.NewHttpClientBindIP(bind, 5*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, body, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp, body, nil
+}
+
+// unwrapJSONP 去掉 callback(...) 包装，非 JSONP 时原样返回
+func unwrapJSONP(body []byte) []byte {
+	body = bytes.TrimSpace(body)
+	start := bytes.IndexByte(body, '(')
+	end := bytes.LastIndexByte(body, ')')
+	if start < 0 || end < start || bytes.HasPrefix(body, []byte("{")) {
+		return body
+	}
+	return body[start+1 : end]
+}
@@ -0,0 +1,30 @@
+package portal
+
+import (
+	"testing"
+
+	"github.com/summonhim/gzgspd/nnet"
+)
+
+// testBinding 从 127.0.0.1 发送请求的绑定，测试结束时关闭
+func testBinding(t *testing.T) *nnet.Binding {
+	bind := nnet.NewBinding("127.0.0.1", nnet.BindOptions{})
+	t.Cleanup(bind.Close)
+	return bind
+}
+
+func TestUnwrapJSONP(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`dr1003({"result":1});`, `{"result":1}`},
+		{` jQuery123_456({"error":"ok"}) `, `{"error":"ok"}`},
+		{`{"result":"success","message":"a(b)"}`, `{"result":"success","message":"a(b)"}`},
+	}
+	for _, tt := range tests {
+		if got := string(unwrapJSONP([]byte(tt.body))); got != tt.want {
+			t.Errorf("unwrapJSONP(%q) = %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package portal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/summonhim/gzgspd/nnet"
+)
+
+// RuijieResponse 锐捷 InterFace.do 登录、登出与在线查询返回的结构
+type RuijieResponse struct {
+	UserIndex         string `json:"userIndex"`
+	Result            string `json:"result"`
+	Message           string `json:"message"`
+	UserName          string `json:"userName"`
+	KeepaliveInterval int    `json:"keepaliveInterval"`
+}
+
+// RuijiePageInfoResponse 锐捷 pageInfo 返回的结构，包含密码加密使用的 RSA 公钥
+type RuijiePageInfoResponse struct {
+	PasswordEncrypt   string `json:"passwordEncrypt"`
+	PublicKeyExponent string `json:"publicKeyExponent"`
+	PublicKeyModulus  string `json:"publicKeyModulus"`
+}
+
+// RuijiePortalChecker 检查当前网络是否需要登录，返回检测经过的跳转与登录链接
+func RuijiePortalChecker(bind *nnet.Binding, jar http.CookieJar, kAliveLink string) *PortalWalk {
+	return WalkPortal(bind, jar, kAliveLink, func(link *url.URL) bool {
+		return strings.Contains(link.Path, "/eportal/")
+	})
+}
+
+// ruijieInterface 调用 InterFace.do 的方法
+func ruijieInterface(bind *nnet.Binding, baseURL string, user_agent string, method string, data url.Values, result interface{}) error {
+	req, err := http.NewRequest("POST", baseURL+"/eportal/InterFace.do?method="+method, strings.NewReader(data.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", user_agent)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+
+	_, body, err := doRequest(bind, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("invalid response from %s: %v", method, err)
+	}
+	return nil
+}
+
+// RuijiePageInfo 获取登录页信息，判断密码是否需要加密
+func RuijiePageInfo(bind *nnet.Binding, baseURL string, user_agent string, queryString string) (*RuijiePageInfoResponse, error) {
+	data := url.Values{}
+	data.Set("queryString", url.QueryEscape(queryString))
+
+	var result RuijiePageInfoResponse
+	if err := ruijieInterface(bind, baseURL, user_agent, "pageInfo", data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RuijieLogin 登录
+// queryString 为跳转链接中 ? 之后的部分，与页面脚本一致再编码一次后提交
+// passwordEncrypt 为 true 时 password 应为 RuijieEncryptPassword 的结果
+func RuijieLogin(
+	bind *nnet.Binding,
+	baseURL string,
+	user_agent string,
+	userId string,
+	password string,
+	service string,
+	queryString string,
+	passwordEncrypt bool,
+) (*RuijieResponse, error) {
+	data := url.Values{}
+	data.Set("userId", userId)
+	data.Set("password", password)
+	data.Set("service", url.QueryEscape(service))
+	data.Set("queryString", url.QueryEscape(queryString))
+	data.Set("operatorPwd", "")
+	data.Set("operatorUserId", "")
+	data.Set("validcode", "")
+	data.Set("passwordEncrypt", fmt.Sprintf("%t", passwordEncrypt))
+
+	var result RuijieResponse
+	if err := ruijieInterface(bind, baseURL, user_agent, "login", data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RuijieLogout 登出
+func RuijieLogout(bind *nnet.Binding, baseURL string, user_agent string, userIndex string) (*RuijieResponse, error) {
+	data := url.Values{}
+	data.Set("userIndex", userIndex)
+
+	var result RuijieResponse
+	if err := ruijieInterface(bind, baseURL, user_agent, "logout", data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RuijieOnlineUserInfo 按 userIndex 查询在线信息，result 为 success 时在线
+func RuijieOnlineUserInfo(bind *nnet.Binding, baseURL string, user_agent string, userIndex string) (*RuijieResponse, error) {
+	data := url.Values{}
+	data.Set("userIndex", userIndex)
+
+	var result RuijieResponse
+	if err := ruijieInterface(bind, baseURL, user_agent, "getOnlineUserInfo", data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RuijieEncryptPassword 按登录页 RSA.js 的方式加密密码
+// 明文为 密码>MAC 反转后的字符串（无 MAC 时为 111111111），以 16 位为一组按小端序分块，
+// 每块做无填充的 RSA 运算后输出十六进制，块之间以空格分隔
+func RuijieEncryptPassword(password string, mac string, exponent string, modulus string) (string, error) {
+	e, ok := new(big.Int).SetString(exponent, 16)
+	if !ok {
+		return "", fmt.Errorf("invalid public key exponent '%s'", exponent)
+	}
+	m, ok := new(big.Int).SetString(modulus, 16)
+	if !ok || m.Sign() <= 0 {
+		return "", fmt.Errorf("invalid public key modulus '%s'", modulus)
+	}
+
+	if mac == "" {
+		mac = "111111111"
+	}
+	plain := []byte(password + ">" + mac)
+	for i, j := 0, len(plain)-1; i < j; i, j = i+1, j-1 {
+		plain[i], plain[j] = plain[j], plain[i]
+	}
+
+	// RSA.js 的 chunkSize 为 2 * biHighIndex(m)，即模数最高非零 16 位组的下标的两倍
+	chunkSize := 2 * ((m.BitLen() - 1) / 16)
+	if chunkSize <= 0 {
+		return "", fmt.Errorf("public key modulus is too short")
+	}
+	for len(plain)%chunkSize != 0 {
+		plain = append(plain, 0)
+	}
+
+	var blocks []string
+	for i := 0; i < len(plain); i += chunkSize {
+		// 小端序转为大端序
+		chunk := make([]byte, chunkSize)
+		for j := 0; j < chunkSize; j++ {
+			chunk[chunkSize-1-j] = plain[i+j]
+		}
+		c := new(big.Int).Exp(new(big.Int).SetBytes(chunk), e, m)
+
+		// biToHex 按 16 位组输出，最高组同样补足 4 位
+		text := c.Text(16)
+		if n := len(text) % 4; n != 0 {
+			text = strings.Repeat("0", 4-n) + text
+		}
+		blocks = append(blocks, text)
+	}
+	return strings.Join(blocks, " "), nil
+}
@@ -0,0 +1,200 @@
+package portal
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	ruijieTestQuery     = "wlanuserip=10.30.1.2&wlanacname=RG-NBR&ssid=&nasip=172.16.254.2&mac=a45e60123456&url=http%3A%2F%2F3.3.3.3%2F"
+	ruijieTestUser      = "2023001"
+	ruijieTestPassword  = "p@ss>word"
+	ruijieTestUserIndex = "3131302e33302e312e32"
+)
+
+// ruijieDecryptPassword 按 RSA.js 的分块方式解密 RuijieEncryptPassword 的结果，返回 密码>MAC
+func ruijieDecryptPassword(t *testing.T, key *rsa.PrivateKey, encrypted string) string {
+	t.Helper()
+	chunkSize := 2 * ((key.N.BitLen() - 1) / 16)
+	var plain []byte
+	for _, block := range strings.Split(encrypted, " ") {
+		c, ok := new(big.Int).SetString(block, 16)
+		if !ok {
+			t.Fatalf("invalid block %q", block)
+		}
+		chunk := new(big.Int).Exp(c, key.D, key.N).FillBytes(make([]byte, chunkSize))
+		// 大端序转回小端序
+		slices.Reverse(chunk)
+		plain = append(plain, chunk...)
+	}
+	plain = []byte(strings.TrimRight(string(plain), "\x00"))
+	slices.Reverse(plain)
+	return string(plain)
+}
+
+// newRuijieServer 模拟锐捷 InterFace.do，要求密码以 pageInfo 返回的公钥加密
+func newRuijieServer(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	online := false
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/eportal/InterFace.do" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		reply := func(v interface{}) {
+			json.NewEncoder(w).Encode(v)
+		}
+		fail := func(msg string) {
+			reply(RuijieResponse{Result: "fail", Message: msg})
+		}
+		// 页面脚本将 queryString 再编码一次后提交
+		queryOK := r.PostForm.Get("queryString") == url.QueryEscape(ruijieTestQuery)
+
+		switch r.URL.Query().Get("method") {
+		case "pageInfo":
+			if !queryOK {
+				fail("queryString mismatch")
+				return
+			}
+			reply(RuijiePageInfoResponse{
+				PasswordEncrypt:   "true",
+				PublicKeyExponent: fmt.Sprintf("%x", key.E),
+				PublicKeyModulus:  key.N.Text(16),
+			})
+		case "login":
+			want := ruijieTestPassword + ">a45e60123456"
+			switch {
+			case !queryOK:
+				fail("queryString mismatch")
+			case r.PostForm.Get("userId") != ruijieTestUser:
+				fail("userId mismatch")
+			case r.PostForm.Get("passwordEncrypt") != "true":
+				fail("password not encrypted")
+			case ruijieDecryptPassword(t, key, r.PostForm.Get("password")) != want:
+				fail("wrong password")
+			case r.PostForm.Get("service") != url.QueryEscape("校园网"):
+				fail("service mismatch")
+			default:
+				online = true
+				reply(RuijieResponse{Result: "success", UserIndex: ruijieTestUserIndex})
+			}
+		case "getOnlineUserInfo":
+			if online && r.PostForm.Get("userIndex") == ruijieTestUserIndex {
+				reply(RuijieResponse{Result: "success", UserName: ruijieTestUser, UserIndex: ruijieTestUserIndex})
+			} else {
+				fail("用户不在线")
+			}
+		case "logout":
+			if !online || r.PostForm.Get("userIndex") != ruijieTestUserIndex {
+				fail("用户不在线")
+				return
+			}
+			online = false
+			reply(RuijieResponse{Result: "success", Message: "下线成功"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestRuijieFakeServer(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newRuijieServer(t, key)
+	defer server.Close()
+	bind, ua := testBinding(t), "test"
+
+	pageInfo, err := RuijiePageInfo(bind, server.URL, ua, ruijieTestQuery)
+	if err != nil || pageInfo.PasswordEncrypt != "true" {
+		t.Fatalf("RuijiePageInfo() = %+v, %v", pageInfo, err)
+	}
+	query, _ := url.ParseQuery(ruijieTestQuery)
+	password, err := RuijieEncryptPassword(ruijieTestPassword, query.Get("mac"), pageInfo.PublicKeyExponent, pageInfo.PublicKeyModulus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login, err := RuijieLogin(bind, server.URL, ua, ruijieTestUser, password, "校园网", ruijieTestQuery, true)
+	if err != nil || login.Result != "success" || login.UserIndex != ruijieTestUserIndex {
+		t.Fatalf("RuijieLogin() = %+v, %v", login, err)
+	}
+	info, err := RuijieOnlineUserInfo(bind, server.URL, ua, login.UserIndex)
+	if err != nil || info.Result != "success" || info.UserName != ruijieTestUser {
+		t.Fatalf("RuijieOnlineUserInfo() = %+v, %v", info, err)
+	}
+	logout, err := RuijieLogout(bind, server.URL, ua, login.UserIndex)
+	if err != nil || logout.Result != "success" {
+		t.Fatalf("RuijieLogout() = %+v, %v", logout, err)
+	}
+	info, err = RuijieOnlineUserInfo(bind, server.URL, ua, login.UserIndex)
+	if err != nil || info.Result == "success" {
+		t.Errorf("RuijieOnlineUserInfo() after logout = %+v, %v", info, err)
+	}
+
+	// 明文密码无法通过校验
+	login, err = RuijieLogin(bind, server.URL, ua, ruijieTestUser, ruijieTestPassword, "校园网", ruijieTestQuery, false)
+	if err != nil || login.Result == "success" {
+		t.Errorf("RuijieLogin() with plain password = %+v, %v", login, err)
+	}
+}
+
+func TestRuijieEncryptPassword(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exponent, modulus := fmt.Sprintf("%x", key.E), key.N.Text(16)
+	tests := []struct {
+		password string
+		mac      string
+		blocks   int
+	}{
+		{"123456", "a45e60123456", 1},
+		{"123456", "", 1},
+		// 超过一块（126 字节）时分块加密
+		{strings.Repeat("x", 200), "a45e60123456", 2},
+	}
+	for _, tt := range tests {
+		encrypted, err := RuijieEncryptPassword(tt.password, tt.mac, exponent, modulus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(strings.Split(encrypted, " ")); n != tt.blocks {
+			t.Errorf("RuijieEncryptPassword(%q) has %d blocks, want %d", tt.password, n, tt.blocks)
+		}
+		mac := tt.mac
+		if mac == "" {
+			mac = "111111111"
+		}
+		if got := ruijieDecryptPassword(t, key, encrypted); got != tt.password+">"+mac {
+			t.Errorf("decrypted %q, want %q", got, tt.password+">"+mac)
+		}
+	}
+}
+
+func TestRuijiePortalChecker(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 锐捷网关以脚本跳转到登录页
+		fmt.Fprintf(w, "<script>top.self.location.href='%s/eportal/index.jsp?%s'</script>", server.URL, ruijieTestQuery)
+	}))
+	defer server.Close()
+
+	walk := RuijiePortalChecker(testBinding(t), nil, server.URL+"/")
+	if !walk.Found() || !strings.HasSuffix(walk.URL, "/eportal/index.jsp?"+ruijieTestQuery) {
+		t.Errorf("RuijiePortalChecker() = %+v", walk)
+	}
+}
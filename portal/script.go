@@ -0,0 +1,196 @@
+package portal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/robertkrimen/otto"
+)
+
+const (
+	// 检测时最多读取的页面长度
+	maxPageSize = 1 << 20
+	// 每个页面的脚本执行总时长
+	scriptTimeout = 500 * time.Millisecond
+	// 执行的脚本总长度上限，超出部分不执行
+	scriptMaxSize = 256 << 10
+	// 最多执行的定时器回调数量
+	scriptMaxTimers = 16
+	// 最多收集的跳转数量与单个链接长度
+	scriptMaxNavigations = 32
+	scriptMaxURLLength   = 4096
+)
+
+var errScriptTimeout = errors.New("script timed out")
+
+// scriptPrelude 模拟浏览器中与跳转有关的对象，所有跳转都交给 __navigate 记录
+const scriptPrelude = `(function (g) {
+	var href = "";
+	function nav(url) { __navigate(String(url)); }
+	function accessor(obj, name) {
+		Object.defineProperty(obj, name, {
+			get: function () { return loc; },
+			set: nav
+		});
+	}
+	var loc = {
+		replace: nav,
+		assign: nav,
+		reload: function () {},
+		toString: function () { return href; }
+	};
+	Object.defineProperty(loc, "href", {
+		get: function () { return href; },
+		set: nav
+	});
+	["protocol", "host", "hostname", "port", "pathname", "search", "hash", "origin"].forEach(function (k) {
+		loc[k] = "";
+	});
+	g.window = g.self = g.top = g.parent = g.frames = g;
+	accessor(g, "location");
+	var element = function () {
+		return {
+			style: {},
+			setAttribute: function () {},
+			getAttribute: function () { return null; },
+			appendChild: function () {},
+			submit: function () {},
+			innerHTML: "",
+			value: ""
+		};
+	};
+	g.document = {
+		cookie: "",
+		referrer: "",
+		title: "",
+		readyState: "complete",
+		write: function () {},
+		writeln: function () {},
+		getElementById: function () { return null; },
+		getElementsByTagName: function () { return []; },
+		getElementsByName: function () { return []; },
+		querySelector: function () { return null; },
+		querySelectorAll: function () { return []; },
+		createElement: element,
+		addEventListener: function () {},
+		body: element(),
+		forms: []
+	};
+	accessor(g.document, "location");
+	g.navigator = { userAgent: "", language: "zh-CN", platform: "", cookieEnabled: true };
+	g.screen = { width: 1920, height: 1080 };
+	g.open = function (url) { if (url) { nav(url); } return null; };
+	g.setTimeout = g.setInterval = function (f) { return __schedule(f); };
+	g.clearTimeout = g.clearInterval = function () {};
+	g.addEventListener = function () {};
+	g.alert = g.confirm = g.prompt = function () {};
+})(this);`
+
+// scriptSandbox 在受限环境中执行门户页面的脚本并收集跳转
+type scriptSandbox struct {
+	vm          *otto.Otto
+	navigations []string
+	// setTimeout 与 setInterval 注册的回调，保存在 Go 中使脚本无法替换
+	timers []otto.Value
+	// 超过 scriptTimeout 后不再执行任何脚本
+	expired atomic.Bool
+}
+
+func newScriptSandbox() (*scriptSandbox, error) {
+	s := &scriptSandbox{vm: otto.New()}
+	s.vm.Interrupt = make(chan func(), 1)
+	s.vm.Set("__navigate", func(call otto.FunctionCall) otto.Value {
+		link := strings.TrimSpace(call.Argument(0).String())
+		if link != "" && len(link) <= scriptMaxURLLength && len(s.navigations) < scriptMaxNavigations {
+			s.navigations = append(s.navigations, link)
+		}
+		return otto.UndefinedValue()
+	})
+	s.vm.Set("__schedule", func(call otto.FunctionCall) otto.Value {
+		if len(s.timers) < scriptMaxTimers {
+			s.timers = append(s.timers, call.Argument(0))
+		}
+		v, _ := otto.ToValue(len(s.timers))
+		return v
+	})
+	if err := s.run(scriptPrelude); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// call 执行 fn，其中的 panic（包括超时中断）作为错误返回，超时后不再执行
+// 所有会执行页面脚本的操作都须经过 call，包括调用回调与转换为字符串
+func (s *scriptSandbox) call(fn func() error) (err error) {
+	if s.expired.Load() {
+		return errScriptTimeout
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("script panicked: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// run 执行一段脚本
+func (s *scriptSandbox) run(src string) error {
+	return s.call(func() error {
+		_, err := s.vm.Run(src)
+		return err
+	})
+}
+
+// runTimers 依次执行注册的回调，回调中注册的也会执行，总数不超过 scriptMaxTimers
+func (s *scriptSandbox) runTimers() {
+	for i := 0; i < len(s.timers) && !s.expired.Load(); i++ {
+		timer := s.timers[i]
+		s.call(func() error {
+			if timer.IsFunction() {
+				_, err := timer.Call(otto.UndefinedValue())
+				return err
+			}
+			_, err := s.vm.Run(timer.String())
+			return err
+		})
+	}
+}
+
+// evalScriptRedirects 在沙箱中执行页面中的脚本与定时器，按顺序返回所有跳转目标
+// 所有脚本共用一个环境，总执行时长不超过 scriptTimeout
+func evalScriptRedirects(html string) []string {
+	s, err := newScriptSandbox()
+	if err != nil {
+		return nil
+	}
+	done := make(chan struct{})
+	defer close(done)
+	timer := time.AfterFunc(scriptTimeout, func() {
+		s.expired.Store(true)
+		// 脚本中的 try/catch 会捕获中断，在执行返回前持续中断
+		halt := func() { panic(errScriptTimeout) }
+		for {
+			select {
+			case s.vm.Interrupt <- halt:
+			case <-done:
+				return
+			}
+		}
+	})
+	defer timer.Stop()
+
+	size := 0
+	for _, m := range scriptRe.FindAllStringSubmatch(html, -1) {
+		size += len(m[1])
+		if size > scriptMaxSize || s.expired.Load() {
+			break
+		}
+		// 单个脚本出错不影响之后的脚本
+		s.run(m[1])
+	}
+	s.runTimers()
+	return s.navigations
+}
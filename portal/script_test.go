@@ -0,0 +1,110 @@
+package portal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestEvalScriptRedirectsPortalPages(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"telecom.html", "https://10.20.16.5/portalScript.do?wlanuserip=10.30.12.45&wlanacname=NFV-BASE-01&mac=a4:5e:60:12:34:56&vlan=1201&hostname=10.20.16.5&rand=6a1f2e&url=http%3A%2F%2F3.3.3.3%2F"},
+		{"ruijie.html", "http://172.16.254.2/eportal/index.jsp?wlanuserip="},
+		{"drcom.html", "http://10.1.1.1:801/eportal/?c=ACSetting&a=Login&wlanuserip=10.20.33.7"},
+		{"srun.html", "http://10.248.98.2/srun_portal_pc?ac_id=5&theme=pro"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := evalScriptRedirects(readTestdata(t, tt.file))
+			if len(got) != 1 || !strings.HasPrefix(got[0], tt.want) {
+				t.Errorf("evalScriptRedirects() = %q, want one link starting with %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageNavigationsMetaRefresh(t *testing.T) {
+	navs := pageNavigations(readTestdata(t, "drcom.html"))
+	var vias []string
+	for _, nav := range navs {
+		vias = append(vias, nav.Via)
+	}
+	if !slices.Equal(vias, []string{HopScript, HopMeta}) {
+		t.Fatalf("pageNavigations() = %+v, want a script and a meta refresh navigation", navs)
+	}
+	if !strings.HasPrefix(navs[1].URL, "http://10.1.1.1/a79.htm?") {
+		t.Errorf("meta refresh = %q", navs[1].URL)
+	}
+}
+
+func TestEvalScriptRedirectsNavigations(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{"location.href", `<script>location.href = "http://a/"</script>`, []string{"http://a/"}},
+		{"window.location", `<script>window.location = "http://a/"</script>`, []string{"http://a/"}},
+		{"top.location", `<script>top.location = "http://a/"</script>`, []string{"http://a/"}},
+		{"document.location.href", `<script>document.location.href = "http://a/" + "b"</script>`, []string{"http://a/b"}},
+		{"assign", `<script>self.location.assign("http://a/")</script>`, []string{"http://a/"}},
+		{"window.open", `<script>window.open("http://a/")</script>`, []string{"http://a/"}},
+		{"string timer", `<script>setTimeout("location.replace('http://a/')", 100)</script>`, []string{"http://a/"}},
+		{"in order", `<script>location.href = "http://a/"</script><script>setTimeout(function () { location.href = "http://c/" }, 0); location.replace("http://b/")</script>`, []string{"http://a/", "http://b/", "http://c/"}},
+		{"error in earlier script", `<script>syntax error(</script><script>location.href = "http://a/"</script>`, []string{"http://a/"}},
+		{"no navigation", `<script>var x = location.href.indexOf("a")</script>`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evalScriptRedirects(tt.html); !slices.Equal(got, tt.want) {
+				t.Errorf("evalScriptRedirects() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalScriptRedirectsHostile(t *testing.T) {
+	huge := "<script>var s = '" + strings.Repeat("x", scriptMaxSize) + "'; location.href = 'http://huge/'</script>"
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{"infinite loop", `<script>for (;;) {}</script><script>location.href = "http://after/"</script>`, nil},
+		{"caught infinite loop", `<script>try { for (;;) {} } catch (e) {}</script><script>for (;;) {}</script>`, nil},
+		{"loop in catch", `<script>for (;;) { try { for (;;) {} } catch (e) {} }</script>`, nil},
+		{"recursive catch", `<script>function f() { try { for (;;) {} } catch (e) { f() } } f()</script>`, nil},
+		{"looping timer", `<script>function f() { setTimeout(f, 0); for (;;) {} } setTimeout(f, 0)</script>`, nil},
+		{"timer in catch", `<script>setTimeout(function () { try { for (;;) {} } catch (e) { for (;;) {} } }, 0)</script>`, nil},
+		{"navigate then hang", `<script>location.href = "http://first/"; for (;;) {}</script>`, []string{"http://first/"}},
+		{"replaced scheduler", `<script>__schedule = null; setTimeout = function () {}; location.href = "http://a/"</script>`, []string{"http://a/"}},
+		{"huge script", huge, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			got := evalScriptRedirects(tt.html)
+			if elapsed := time.Since(start); elapsed > scriptTimeout+time.Second {
+				t.Errorf("evalScriptRedirects() took %v", elapsed)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("evalScriptRedirects() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package portal
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/summonhim/gzgspd/nnet"
+)
+
+// SrunResponse 深澜 cgi-bin 接口返回的结构
+type SrunResponse struct {
+	Error     string `json:"error"`
+	ErrorMsg  string `json:"error_msg"`
+	Res       string `json:"res"`
+	SucMsg    string `json:"suc_msg"`
+	PloyMsg   string `json:"ploy_msg"`
+	Challenge string `json:"challenge"`
+	ClientIP  string `json:"client_ip"`
+	OnlineIP  string `json:"online_ip"`
+	UserName  string `json:"user_name"`
+}
+
+// Message 返回可读的错误信息
+func (r *SrunResponse) Message() string {
+	for _, msg := range []string{r.ErrorMsg, r.PloyMsg, r.Res, r.Error} {
+		if msg != "" {
+			return msg
+		}
+	}
+	return "unknown error"
+}
+
+const (
+	// 深澜 portal 使用的自定义 base64 字母表
+	srunAlphabet = "LVoJPiCN2R8G90yg+hmFHuacZ1OWMnrsSTXkYpUq/3dlbfKwv6xztjI7DeBE45QA"
+	srunEncVer   = "srun_bx1"
+	srunN        = "200"
+	srunType     = "1"
+)
+
+var srunEncoding = base64.NewEncoding(srunAlphabet)
+
+// 深澜跳转链接的特征，如 /srun_portal_pc?ac_id=1 或 /index_1.html
+var (
+	srunRedirectRe = regexp.MustCompile(`srun_portal|[?&]ac_id=|/index_\d+\.html`)
+	srunIndexRe    = regexp.MustCompile(`/index_(\d+)\.html`)
+)
+
+// SrunPortalChecker 检查当前网络是否需要登录，返回检测经过的跳转与登录链接
+func SrunPortalChecker(bind *nnet.Binding, jar http.CookieJar, kAliveLink string) *PortalWalk {
+	return WalkPortal(bind, jar, kAliveLink, func(link *url.URL) bool {
+		return srunRedirectRe.MatchString(link.String())
+	})
+}
+
+// SrunAcID 从跳转链接中取得 ac_id，没有时返回空
+func SrunAcID(link *url.URL) string {
+	if acID := link.Query().Get("ac_id"); acID != "" {
+		return acID
+	}
+	if m := srunIndexRe.FindStringSubmatch(link.Path); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// srunRequest 以 JSONP 方式调用 cgi-bin 接口
+func srunRequest(bind *nnet.Binding, baseURL string, user_agent string, path string, params url.Values) (*SrunResponse, error) {
+	ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	params.Set("callback", "jQuery112406118340540763985_"+ts)
+	params.Set("_", ts)
+
+	req, err := http.NewRequest("GET", baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", user_agent)
+	req.Header.Set("Accept", "text/javascript, application/javascript, */*")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+
+	_, body, err := doRequest(bind, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result SrunResponse
+	if err := json.Unmarshal(unwrapJSONP(body), &result); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %v", path, err)
+	}
+	return &result, nil
+}
+
+// SrunUserInfo 查询在线信息，error 为 ok 时在线
+func SrunUserInfo(bind *nnet.Binding, baseURL string, user_agent string) (*SrunResponse, error) {
+	return srunRequest(bind, baseURL, user_agent, "/cgi-bin/rad_user_info", url.Values{})
+}
+
+// SrunChallenge 获取登录使用的 challenge（token）
+func SrunChallenge(bind *nnet.Binding, baseURL string, user_agent string, username string, ip string) (*SrunResponse, error) {
+	params := url.Values{}
+	params.Set("username", username)
+	params.Set("ip", ip)
+	return srunRequest(bind, baseURL, user_agent, "/cgi-bin/get_challenge", params)
+}
+
+// SrunLogin 使用 challenge 登录
+// ip 为 portal 看到的客户端地址，通常取 get_challenge 返回的 client_ip
+func SrunLogin(
+	bind *nnet.Binding,
+	baseURL string,
+	user_agent string,
+	username string,
+	password string,
+	acID string,
+	ip string,
+	token string,
+) (*SrunResponse, error) {
+	info, err := srunInfo(username, password, ip, acID, token)
+	if err != nil {
+		return nil, err
+	}
+	hmd5 := srunHMD5(password, token)
+
+	params := url.Values{}
+	params.Set("action", "login")
+	params.Set("username", username)
+	params.Set("password", "{MD5}"+hmd5)
+	params.Set("os", "Windows 10")
+	params.Set("name", "Windows")
+	params.Set("double_stack", "0")
+	params.Set("chksum", srunChecksum(token, username, hmd5, acID, ip, info))
+	params.Set("info", info)
+	params.Set("ac_id", acID)
+	params.Set("ip", ip)
+	params.Set("n", srunN)
+	params.Set("type", srunType)
+	return srunRequest(bind, baseURL, user_agent, "/cgi-bin/srun_portal", params)
+}
+
+// SrunLogout 登出
+func SrunLogout(bind *nnet.Binding, baseURL string, user_agent string, username string, acID string, ip string) (*SrunResponse, error) {
+	params := url.Values{}
+	params.Set("action", "logout")
+	params.Set("username", username)
+	params.Set("ac_id", acID)
+	params.Set("ip", ip)
+	return srunRequest(bind, baseURL, user_agent, "/cgi-bin/srun_portal", params)
+}
+
+// srunHMD5 portal 脚本中的 md5(password, token)，即以 token 为密钥的 HMAC-MD5
+func srunHMD5(password string, token string) string {
+	mac := hmac.New(md5.New, []byte(token))
+	mac.Write([]byte(password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// srunInfo 登录参数 info：{SRBX1} 加自定义 base64 编码的 xEncode(JSON, token)
+func srunInfo(username string, password string, ip string, acID string, token string) (string, error) {
+	// 字段顺序与页面脚本 JSON.stringify 的结果一致
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		IP       string `json:"ip"`
+		AcID     string `json:"acid"`
+		EncVer   string `json:"enc_ver"`
+	}{username, password, ip, acID, srunEncVer})
+	if err != nil {
+		return "", err
+	}
+	data := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	return "{SRBX1}" + srunEncoding.EncodeToString(srunXEncode(data, []byte(token))), nil
+}
+
+// srunChecksum 登录参数 chksum
+func srunChecksum(token string, username string, hmd5 string, acID string, ip string, info string) string {
+	var sb strings.Builder
+	for _, s := range []string{username, hmd5, acID, ip, srunN, srunType, info} {
+		sb.WriteString(token)
+		sb.WriteString(s)
+	}
+	sum := sha1.Sum([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// srunWords 按小端序将字节转为 32 位整数数组，includeLen 时末尾追加原长度
+func srunWords(b []byte, includeLen bool) []uint32 {
+	n := (len(b) + 3) / 4
+	v := make([]uint32, n, n+1)
+	padded := make([]byte, n*4)
+	copy(padded, b)
+	for i := range v {
+		v[i] = binary.LittleEndian.Uint32(padded[i*4:])
+	}
+	if includeLen {
+		v = append(v, uint32(len(b)))
+	}
+	return v
+}
+
+// srunXEncode portal 脚本中的 xEncode，是 XXTEA 的变体
+func srunXEncode(data []byte, key []byte) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+	v := srunWords(data, true)
+	k := srunWords(key, false)
+	for len(k) < 4 {
+		k = append(k, 0)
+	}
+
+	n := len(v) - 1
+	z := v[n]
+	var y, m, e, d uint32
+	const c = 0x9E3779B9
+	for q := 6 + 52/(n+1); q > 0; q-- {
+		d += c
+		e = d >> 2 & 3
+		p := 0
+		for ; p < n; p++ {
+			y = v[p+1]
+			m = z>>5 ^ y<<2
+			m += (y>>3 ^ z<<4) ^ (d ^ y)
+			m += k[uint32(p&3)^e] ^ z
+			v[p] += m
+			z = v[p]
+		}
+		y = v[0]
+		m = z>>5 ^ y<<2
+		m += (y>>3 ^ z<<4) ^ (d ^ y)
+		m += k[uint32(p&3)^e] ^ z
+		v[n] += m
+		z = v[n]
+	}
+
+	out := make([]byte, len(v)*4)
+	for i, w := range v {
+		binary.LittleEndian.PutUint32(out[i*4:], w)
+	}
+	return out
+}
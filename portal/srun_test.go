@@ -0,0 +1,185 @@
+package portal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// 以下向量由 portal 页面脚本中的 xEncode、base64 与 md5 函数生成
+const (
+	srunTestToken    = "8f5bca1b6e6c1d3f0f38e0b4d2b8a6c9e6b8f3f7f1c8b3d2a9e8c7b6a5f4e3d2"
+	srunTestUser     = "2023001"
+	srunTestPassword = "p@ss"
+	srunTestIP       = "10.0.0.5"
+	srunTestAcID     = "1"
+	srunTestInfo     = "{SRBX1}5FRnqcVd/F63xk8sbcCN3H+p3eUHhwmzMaJYB2VysFHbABaGp/pQ1rYMtfTj8BgLGJ00aLHTj3OVj9s0vz7AqitnIPcBCHtufd0uFBlTsE5/kTXkRiCvRGRzj2t="
+	srunTestHMD5     = "35748f145a9d678c934e8e2a6d1d7ef4"
+	srunTestChksum   = "b4e09e3e045a70d5f2664a7b4a590e4f2fdc3000"
+)
+
+func TestSrunXEncode(t *testing.T) {
+	tests := []struct {
+		data string
+		key  string
+		want string
+	}{
+		{"hello world", "0123456789abcdef", "2d325ef3aaf1546308a57508d6b613e2"},
+		{"a", "k", "10d188dc61522d85"},
+		{
+			`{"username":"2023001","password":"p@ss","ip":"10.0.0.5","acid":"1","enc_ver":"srun_bx1"}`,
+			srunTestToken,
+			"f5325d9d706aa13c69ca329fb17187a54425a7999446f4b37160e4e8804e7d352cffa58b96897e65e91cd2d8752ba3c02c334d580521d69681d4c7cdc33dff9c5d1dd845fa194d15b6a3554faae17fbf688e18a32451b024b273d48d",
+		},
+		{"", "key", ""},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(srunXEncode([]byte(tt.data), []byte(tt.key))); got != tt.want {
+			t.Errorf("srunXEncode(%q, %q) = %s, want %s", tt.data, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSrunEncoding(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"\x00\x01\x02\xfd\xfe\xff", "LLPoAsEA"},
+		{"srun", "M7RjWS=="},
+		{"ab", "Za2="},
+	}
+	for _, tt := range tests {
+		if got := srunEncoding.EncodeToString([]byte(tt.data)); got != tt.want {
+			t.Errorf("srunEncoding(%q) = %s, want %s", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestSrunLoginParams(t *testing.T) {
+	info, err := srunInfo(srunTestUser, srunTestPassword, srunTestIP, srunTestAcID, srunTestToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != srunTestInfo {
+		t.Errorf("srunInfo() = %s, want %s", info, srunTestInfo)
+	}
+	hmd5 := srunHMD5(srunTestPassword, srunTestToken)
+	if hmd5 != srunTestHMD5 {
+		t.Errorf("srunHMD5() = %s, want %s", hmd5, srunTestHMD5)
+	}
+	if got := srunChecksum(srunTestToken, srunTestUser, hmd5, srunTestAcID, srunTestIP, info); got != srunTestChksum {
+		t.Errorf("srunChecksum() = %s, want %s", got, srunTestChksum)
+	}
+}
+
+// newSrunServer 模拟深澜 cgi-bin 接口，登录参数须与页面脚本的计算结果一致
+func newSrunServer(t *testing.T) *httptest.Server {
+	online := false
+	reply := func(w http.ResponseWriter, q url.Values, v map[string]string) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "%s(%s)", q.Get("callback"), data)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("callback") == "" {
+			t.Errorf("%s without callback", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/cgi-bin/get_challenge":
+			reply(w, q, map[string]string{"error": "ok", "challenge": srunTestToken, "client_ip": srunTestIP})
+		case "/cgi-bin/srun_portal":
+			switch q.Get("action") {
+			case "login":
+				want := map[string]string{
+					"username": srunTestUser,
+					"password": "{MD5}" + srunTestHMD5,
+					"info":     srunTestInfo,
+					"chksum":   srunTestChksum,
+					"ac_id":    srunTestAcID,
+					"ip":       srunTestIP,
+					"n":        "200",
+					"type":     "1",
+				}
+				for k, v := range want {
+					if q.Get(k) != v {
+						reply(w, q, map[string]string{"error": "login_error", "error_msg": k + " mismatch"})
+						return
+					}
+				}
+				online = true
+				reply(w, q, map[string]string{"error": "ok", "suc_msg": "login_ok", "client_ip": srunTestIP})
+			case "logout":
+				if q.Get("username") != srunTestUser || q.Get("ip") != srunTestIP {
+					reply(w, q, map[string]string{"error": "logout_error"})
+					return
+				}
+				online = false
+				reply(w, q, map[string]string{"error": "ok"})
+			}
+		case "/cgi-bin/rad_user_info":
+			if online {
+				reply(w, q, map[string]string{"error": "ok", "user_name": srunTestUser, "online_ip": srunTestIP})
+			} else {
+				reply(w, q, map[string]string{"error": "not_online_error"})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestSrunFakeServer(t *testing.T) {
+	server := newSrunServer(t)
+	defer server.Close()
+	bind, ua := testBinding(t), "test"
+
+	info, err := SrunUserInfo(bind, server.URL, ua)
+	if err != nil || info.Error != "not_online_error" {
+		t.Fatalf("SrunUserInfo() = %+v, %v, want offline", info, err)
+	}
+
+	challenge, err := SrunChallenge(bind, server.URL, ua, srunTestUser, bind.IP())
+	if err != nil || challenge.Challenge != srunTestToken {
+		t.Fatalf("SrunChallenge() = %+v, %v", challenge, err)
+	}
+	login, err := SrunLogin(bind, server.URL, ua, srunTestUser, srunTestPassword, srunTestAcID, challenge.ClientIP, challenge.Challenge)
+	if err != nil || login.Error != "ok" {
+		t.Fatalf("SrunLogin() = %+v, %v", login, err)
+	}
+
+	info, err = SrunUserInfo(bind, server.URL, ua)
+	if err != nil || info.Error != "ok" || info.OnlineIP != srunTestIP {
+		t.Fatalf("SrunUserInfo() = %+v, %v, want online", info, err)
+	}
+
+	logout, err := SrunLogout(bind, server.URL, ua, srunTestUser, srunTestAcID, srunTestIP)
+	if err != nil || logout.Error != "ok" {
+		t.Fatalf("SrunLogout() = %+v, %v", logout, err)
+	}
+	info, err = SrunUserInfo(bind, server.URL, ua)
+	if err != nil || info.Error != "not_online_error" {
+		t.Errorf("SrunUserInfo() after logout = %+v, %v", info, err)
+	}
+}
+
+func TestSrunAcID(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"http://10.248.98.2/srun_portal_pc?ac_id=5&theme=pro", "5"},
+		{"http://10.248.98.2/index_12.html?url=x", "12"},
+		{"http://10.248.98.2/", ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.link)
+		if got := SrunAcID(u); got != tt.want {
+			t.Errorf("SrunAcID(%s) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
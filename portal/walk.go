@@ -0,0 +1,167 @@
+package portal
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/summonhim/gzgspd/nnet"
+)
+
+// walkMaxHops 检测时最多跟随的跳转次数
+const walkMaxHops = 8
+
+// 到达某一地址的方式
+const (
+	HopStart    = "start"
+	HopRedirect = "redirect"
+	HopMeta     = "meta"
+	HopScript   = "script"
+)
+
+// PortalHop 检测过程中请求过的一个地址
+type PortalHop struct {
+	URL    string
+	Via    string
+	Status int // 响应状态码，请求失败或门户链接本身未请求时为 0
+}
+
+// PortalWalk 检测结果，URL 为空表示没有跳转到门户
+type PortalWalk struct {
+	Chain  []PortalHop
+	URL    string
+	Params url.Values
+}
+
+// Found 是否跳转到了门户
+func (w *PortalWalk) Found() bool {
+	return w.URL != ""
+}
+
+// pageNavigation 页面中的一个跳转
+type pageNavigation struct {
+	URL string
+	Via string
+}
+
+// pageNavigations 返回页面中所有跳转，依次为脚本执行结果、location.href 赋值与 meta refresh
+// 脚本执行失败时 location.href 赋值仍可由正则匹配到
+func pageNavigations(html string) []pageNavigation {
+	var navs []pageNavigation
+	seen := map[string]bool{}
+	add := func(link string, via string) {
+		if !seen[link] {
+			seen[link] = true
+			navs = append(navs, pageNavigation{URL: link, Via: via})
+		}
+	}
+	for _, link := range evalScriptRedirects(html) {
+		add(link, HopScript)
+	}
+	for _, m := range scriptRedirectRe.FindAllStringSubmatch(html, -1) {
+		add(m[1], HopScript)
+	}
+	for _, m := range metaRefreshRe.FindAllStringSubmatch(html, -1) {
+		add(m[1], HopMeta)
+	}
+	return navs
+}
+
+// pageRedirects 返回页面中所有跳转链接
+func pageRedirects(html string) []string {
+	var links []string
+	for _, nav := range pageNavigations(html) {
+		links = append(links, nav.URL)
+	}
+	return links
+}
+
+// WalkPortal 从 kAliveLink 开始依次跟随 3xx、meta refresh 与脚本跳转，最多 walkMaxHops 次
+// 遇到 accept 接受的链接时即为门户，jar 不为空时保存途中的 Cookie
+func WalkPortal(bind *nnet.Binding, jar http.CookieJar, kAliveLink string, accept func(link *url.URL) bool) *PortalWalk {
+	walk := &PortalWalk{}
+
+	client, err := nnet.NewHttpClientBindIP(bind, 5*time.Second)
+	if err != nil {
+		return walk
+	}
+	client.Jar = jar
+
+	link, via := kAliveLink, HopStart
+	visited := map[string]bool{}
+	for hop := 0; hop <= walkMaxHops; hop++ {
+		current, err := url.Parse(link)
+		if err != nil {
+			return walk
+		}
+		walk.Chain = append(walk.Chain, PortalHop{URL: link, Via: via})
+		if hop > 0 && accept(current) {
+			walk.URL = link
+			walk.Params = current.Query()
+			return walk
+		}
+		// 避免在几个页面之间来回跳转
+		if visited[link] {
+			return walk
+		}
+		visited[link] = true
+
+		next, nextVia := walkStep(client, current, hop == 0, walk, accept)
+		if next == nil {
+			return walk
+		}
+		link, via = next.String(), nextVia
+	}
+	return walk
+}
+
+// walkStep 请求 current，返回下一跳的地址与方式，没有下一跳时返回 nil
+// 页面中有多个跳转时优先选择 accept 接受的链接
+// fresh 时请求后关闭连接，使每次检测都新建连接，下线后网关通常只拦截新建的连接
+func walkStep(client *http.Client, current *url.URL, fresh bool, walk *PortalWalk, accept func(link *url.URL) bool) (*url.URL, string) {
+	req, err := http.NewRequest("GET", current.String(), nil)
+	if err != nil {
+		return nil, ""
+	}
+	req.Close = fresh
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, ""
+	}
+	defer resp.Body.Close()
+	walk.Chain[len(walk.Chain)-1].Status = resp.StatusCode
+
+	// 3xx 重定向
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		loc, err := resp.Location()
+		if err != nil {
+			return nil, ""
+		}
+		return loc, HopRedirect
+	}
+
+	// 200 页面中的脚本跳转或 meta refresh
+	if resp.StatusCode != 200 {
+		return nil, ""
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, ""
+	}
+	var first *url.URL
+	var firstVia string
+	for _, nav := range pageNavigations(string(body)) {
+		next, err := current.Parse(nav.URL)
+		if err != nil || (next.Scheme != "http" && next.Scheme != "https") {
+			continue
+		}
+		if accept(next) {
+			return next, nav.Via
+		}
+		if first == nil {
+			first, firstVia = next, nav.Via
+		}
+	}
+	return first, firstVia
+}